@@ -1,12 +1,24 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
 	"github.com/syseleven/terraform-provider-metakube/metakube"
 )
 
 func main() {
+	// kubeconfigs written by the provider may run it as credential plugin
+	if len(os.Args) > 1 && os.Args[1] == metakube.KubeconfigTokenFileCommand {
+		if err := metakube.RunKubeconfigTokenFile(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: func() *schema.Provider {
 			return metakube.Provider()
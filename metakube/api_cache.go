@@ -0,0 +1,146 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/runtime"
+)
+
+// apiCacheTTL lists the read-mostly operations that are cached and for how long.
+var apiCacheTTL = map[string]time.Duration{
+	"listDatacentersV2": 10 * time.Minute,
+	"getMasterVersions": 10 * time.Minute,
+	"listProjects":      time.Minute,
+	"getClusterV2":      15 * time.Second,
+}
+
+// apiCache keeps replies of read-mostly operations, so resources and data
+// sources looking up the same data during one run don't request it again.
+// Entries are dropped once they expire or a mutating request is sent for
+// the same or a nested path. Replies are kept encoded, every hit decodes a
+// copy callers are free to change.
+type apiCache struct {
+	mu      sync.Mutex
+	entries map[string]apiCacheEntry
+	now     func() time.Time
+}
+
+type apiCacheEntry struct {
+	path    string
+	typ     reflect.Type
+	value   []byte
+	expires time.Time
+}
+
+type apiCacheBypassKey struct{}
+
+// withoutAPICache returns a context whose requests skip the cache, for polls
+// that must see changes as soon as the API does. Their replies still
+// refresh the cache.
+func withoutAPICache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, apiCacheBypassKey{}, true)
+}
+
+func apiCacheBypassed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(apiCacheBypassKey{}).(bool)
+	return v
+}
+
+func newAPICache() *apiCache {
+	return &apiCache{
+		entries: make(map[string]apiCacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *apiCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	v := reflect.New(e.typ)
+	if err := json.Unmarshal(e.value, v.Interface()); err != nil {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return v.Elem().Interface(), true
+}
+
+// put keeps value unless it can't be encoded.
+func (c *apiCache) put(key, path string, value interface{}, ttl time.Duration) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = apiCacheEntry{
+		path:    path,
+		typ:     reflect.TypeOf(value),
+		value:   b,
+		expires: c.now().Add(ttl),
+	}
+}
+
+// invalidate drops entries for the path, its parents and nested paths.
+func (c *apiCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if isSubPath(path, e.path) || isSubPath(e.path, path) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+func (c *apiCache) middleware(next runtime.ClientTransport) runtime.ClientTransport {
+	return clientTransportFunc(func(op *runtime.ClientOperation) (interface{}, error) {
+		req, err := newOperationRequest(op)
+		if err != nil {
+			return next.Submit(op)
+		}
+		path := req.path()
+
+		if op.Method != http.MethodGet {
+			defer c.invalidate(path)
+			return next.Submit(op)
+		}
+
+		ttl, ok := apiCacheTTL[op.ID]
+		if !ok {
+			return next.Submit(op)
+		}
+		key := op.ID + " " + path + "?" + req.query.Encode()
+		if !apiCacheBypassed(op.Context) {
+			if v, ok := c.get(key); ok {
+				return v, nil
+			}
+		}
+		v, err := next.Submit(op)
+		if err == nil {
+			c.put(key, path, v, ttl)
+		}
+		return v, err
+	})
+}
+
+// isSubPath reports whether path equals parent or is nested below it.
+func isSubPath(path, parent string) bool {
+	parent = strings.TrimSuffix(parent, "/")
+	return path == parent || strings.HasPrefix(path, parent+"/")
+}
@@ -0,0 +1,202 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syseleven/go-metakube/client/project"
+	"github.com/syseleven/go-metakube/models"
+)
+
+func newTestCachedClient(t *testing.T) (*metakubeProviderMeta, map[string]int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	cache := newAPICache()
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/projects":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"id": "p1", "name": "one"}})
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]string{"id": "c1", "name": "cluster"})
+		}
+	}), cache.middleware)
+	k.cache = cache
+	return k, requests
+}
+
+func TestAPICacheHit(t *testing.T) {
+	k, requests := newTestCachedClient(t)
+
+	for i := 0; i < 3; i++ {
+		r, err := k.client.Project.ListProjects(project.NewListProjectsParams(), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(r.Payload) != 1 || r.Payload[0].ID != "p1" {
+			t.Fatalf("unexpected payload: %+v", r.Payload)
+		}
+	}
+	if got := requests["GET /api/v1/projects"]; got != 1 {
+		t.Fatalf("want 1 request, got %d", got)
+	}
+}
+
+func TestAPICacheKeyIncludesPathParams(t *testing.T) {
+	k, requests := newTestCachedClient(t)
+
+	for _, id := range []string{"c1", "c2", "c1"} {
+		p := project.NewGetClusterV2Params().WithProjectID("p1").WithClusterID(id)
+		if _, err := k.client.Project.GetClusterV2(p, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := requests["GET /api/v2/projects/p1/clusters/c1"]; got != 1 {
+		t.Fatalf("want 1 request for c1, got %d", got)
+	}
+	if got := requests["GET /api/v2/projects/p1/clusters/c2"]; got != 1 {
+		t.Fatalf("want 1 request for c2, got %d", got)
+	}
+}
+
+func TestAPICacheInvalidatedByMutation(t *testing.T) {
+	k, requests := newTestCachedClient(t)
+
+	get := func(id string) {
+		t.Helper()
+		p := project.NewGetClusterV2Params().WithProjectID("p1").WithClusterID(id)
+		if _, err := k.client.Project.GetClusterV2(p, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	get("c1")
+	get("c2")
+	p := project.NewPatchClusterV2Params().WithProjectID("p1").WithClusterID("c1").WithPatch(map[string]string{})
+	if _, err := k.client.Project.PatchClusterV2(p, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	get("c1")
+	get("c2")
+
+	if got := requests["GET /api/v2/projects/p1/clusters/c1"]; got != 2 {
+		t.Fatalf("patched cluster should be fetched again, want 2 requests, got %d", got)
+	}
+	if got := requests["GET /api/v2/projects/p1/clusters/c2"]; got != 1 {
+		t.Fatalf("other cluster should stay cached, want 1 request, got %d", got)
+	}
+}
+
+func TestAPICacheExpiry(t *testing.T) {
+	now := time.Now()
+	c := newAPICache()
+	c.now = func() time.Time { return now }
+
+	c.put("key", "/api/v2/projects/p1/clusters/c1", "value", time.Minute)
+	if v, ok := c.get("key"); !ok || v != "value" {
+		t.Fatalf("want cached value, got %v, %v", v, ok)
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.get("key"); ok {
+		t.Fatal("expected entry to be expired")
+	}
+}
+
+func TestAPICacheInvalidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		path        string
+		invalidate  string
+		wantDropped bool
+	}{
+		{
+			name:        "same path",
+			path:        "/api/v2/projects/p1/clusters/c1",
+			invalidate:  "/api/v2/projects/p1/clusters/c1",
+			wantDropped: true,
+		},
+		{
+			name:        "nested path",
+			path:        "/api/v2/projects/p1/clusters/c1",
+			invalidate:  "/api/v2/projects/p1/clusters/c1/machinedeployments/md1",
+			wantDropped: true,
+		},
+		{
+			name:        "parent path",
+			path:        "/api/v2/projects/p1/clusters/c1",
+			invalidate:  "/api/v2/projects/p1",
+			wantDropped: true,
+		},
+		{
+			name:        "sibling with common prefix",
+			path:        "/api/v2/projects/p1/clusters/c10",
+			invalidate:  "/api/v2/projects/p1/clusters/c1",
+			wantDropped: false,
+		},
+		{
+			name:        "unrelated path",
+			path:        "/api/v2/projects/p1/clusters/c1",
+			invalidate:  "/api/v2/projects/p2/clusters/c1",
+			wantDropped: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newAPICache()
+			c.put("key", tc.path, "value", time.Minute)
+			c.invalidate(tc.invalidate)
+			if _, ok := c.get("key"); ok == tc.wantDropped {
+				t.Fatalf("want dropped %v, got cached %v", tc.wantDropped, ok)
+			}
+		})
+	}
+}
+
+func TestAPICacheReturnsCopies(t *testing.T) {
+	k, requests := newTestCachedClient(t)
+
+	get := func() *models.Cluster {
+		t.Helper()
+		p := project.NewGetClusterV2Params().WithProjectID("p1").WithClusterID("c1")
+		r, err := k.client.Project.GetClusterV2(p, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return r.Payload
+	}
+
+	get().Name = "changed"
+	get().Name = "changed"
+	if got := get().Name; got != "cluster" {
+		t.Fatalf("want cached payload unchanged, got name %q", got)
+	}
+	if got := requests["GET /api/v2/projects/p1/clusters/c1"]; got != 1 {
+		t.Fatalf("want 1 request, got %d", got)
+	}
+}
+
+func TestAPICacheBypass(t *testing.T) {
+	k, requests := newTestCachedClient(t)
+
+	for i := 0; i < 2; i++ {
+		p := project.NewGetClusterV2Params().WithContext(withoutAPICache(context.Background())).WithProjectID("p1").WithClusterID("c1")
+		if _, err := k.client.Project.GetClusterV2(p, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	p := project.NewGetClusterV2Params().WithContext(context.Background()).WithProjectID("p1").WithClusterID("c1")
+	if _, err := k.client.Project.GetClusterV2(p, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := requests["GET /api/v2/projects/p1/clusters/c1"]; got != 2 {
+		t.Fatalf("want polls to skip the cache and refresh it, want 2 requests, got %d", got)
+	}
+}
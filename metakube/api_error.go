@@ -0,0 +1,207 @@
+package metakube
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/runtime"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/syseleven/go-metakube/models"
+)
+
+type apiErrorKind int
+
+const (
+	apiErrorUnknown apiErrorKind = iota
+	apiErrorNotFound
+	apiErrorConflict
+	apiErrorForbidden
+	apiErrorTransient
+	apiErrorValidation
+)
+
+func (k apiErrorKind) String() string {
+	switch k {
+	case apiErrorNotFound:
+		return "not found"
+	case apiErrorConflict:
+		return "conflict"
+	case apiErrorForbidden:
+		return "forbidden"
+	case apiErrorTransient:
+		return "transient"
+	case apiErrorValidation:
+		return "validation"
+	default:
+		return "unknown"
+	}
+}
+
+// Messages of errors that are classified independently of the status code.
+// The API reports them as internal errors, although they only mean the
+// cluster isn't ready yet or the object was changed concurrently.
+var (
+	apiErrorTransientMessages = []string{
+		"failed calling webhook",
+		"Cluster components are not ready yet",
+	}
+	apiErrorConflictMessages = []string{
+		"the object has been modified",
+	}
+)
+
+// status codes used to find the status of generated error types, which
+// implement only IsCode
+var apiErrorProbeCodes = []int{
+	http.StatusBadRequest,
+	http.StatusUnauthorized,
+	http.StatusForbidden,
+	http.StatusNotFound,
+	http.StatusConflict,
+	http.StatusUnprocessableEntity,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// apiError is an error returned by the MetaKube API client.
+type apiError struct {
+	// HTTP status of the reply, 0 if no reply was received
+	status int
+	// code, message and details from the error reply
+	code       int64
+	message    string
+	additional []string
+	kind       apiErrorKind
+
+	err error
+}
+
+// newAPIError returns err as *apiError. It returns nil if err is nil.
+func newAPIError(err error) *apiError {
+	if err == nil {
+		return nil
+	}
+	var e *apiError
+	if errors.As(err, &e) {
+		return e
+	}
+
+	e = &apiError{err: err}
+	switch v := err.(type) {
+	case interface{ Code() int }:
+		e.status = v.Code()
+	case *runtime.APIError:
+		e.status = v.Code
+	case interface{ IsCode(int) bool }:
+		for _, code := range apiErrorProbeCodes {
+			if v.IsCode(code) {
+				e.status = code
+				break
+			}
+		}
+	}
+	if v, ok := err.(interface{ GetPayload() *models.ErrorResponse }); ok {
+		if p := v.GetPayload(); p != nil && p.Error != nil {
+			if p.Error.Code != nil {
+				e.code = *p.Error.Code
+			}
+			if p.Error.Message != nil {
+				e.message = *p.Error.Message
+			}
+			e.additional = p.Error.Additional
+		}
+	}
+	e.kind = e.classify()
+	return e
+}
+
+func (e *apiError) classify() apiErrorKind {
+	text := e.message + " " + strings.Join(e.additional, " ")
+	for _, m := range apiErrorTransientMessages {
+		if strings.Contains(text, m) {
+			return apiErrorTransient
+		}
+	}
+	for _, m := range apiErrorConflictMessages {
+		if strings.Contains(text, m) {
+			return apiErrorConflict
+		}
+	}
+
+	switch e.status {
+	case http.StatusNotFound:
+		return apiErrorNotFound
+	case http.StatusConflict:
+		return apiErrorConflict
+	case http.StatusForbidden:
+		return apiErrorForbidden
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return apiErrorValidation
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return apiErrorTransient
+	}
+	return apiErrorUnknown
+}
+
+func (e *apiError) Error() string {
+	if e.message == "" {
+		return e.err.Error()
+	}
+	if len(e.additional) > 0 {
+		return fmt.Sprintf("%s %v", e.message, e.additional)
+	}
+	return e.message
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+// apiErrorIs reports whether err is an API error of the given kind.
+func apiErrorIs(err error, kind apiErrorKind) bool {
+	if err == nil {
+		return false
+	}
+	return newAPIError(err).kind == kind
+}
+
+// apiErrorStatus returns the HTTP status of the reply or 0 if there was none.
+func apiErrorStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	return newAPIError(err).status
+}
+
+// retryError wraps the error for retry.RetryContext, transient errors are retried.
+func (e *apiError) retryError() *retry.RetryError {
+	if e.kind == apiErrorTransient {
+		return retry.RetryableError(e)
+	}
+	return retry.NonRetryableError(e)
+}
+
+// diagFromAPIError returns a diagnostic for a failed API call. Validation
+// errors are attributed to the given path.
+func diagFromAPIError(err error, summary string, path cty.Path) diag.Diagnostics {
+	e := newAPIError(err)
+	d := diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  fmt.Sprintf("%s: %v", summary, e.err),
+	}
+	if e.message != "" {
+		d.Summary = fmt.Sprintf("%s: %s", summary, e.message)
+		d.Detail = strings.Join(e.additional, "\n")
+	}
+	if e.kind == apiErrorValidation {
+		d.AttributePath = path
+	}
+	return diag.Diagnostics{d}
+}
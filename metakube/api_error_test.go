@@ -0,0 +1,165 @@
+package metakube
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-openapi/runtime"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/syseleven/go-metakube/client/project"
+	"github.com/syseleven/go-metakube/models"
+)
+
+func newTestErrorResponse(code int64, message string, additional ...string) *models.ErrorResponse {
+	return &models.ErrorResponse{
+		Error: &models.ErrorDetails{
+			Code:       &code,
+			Message:    &message,
+			Additional: additional,
+		},
+	}
+}
+
+func newTestGetClusterDefault(code int, payload *models.ErrorResponse) error {
+	e := project.NewGetClusterV2Default(code)
+	e.Payload = payload
+	return e
+}
+
+func TestNewAPIError(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantKind    apiErrorKind
+		wantMessage string
+	}{
+		{
+			name:        "not found",
+			err:         newTestGetClusterDefault(http.StatusNotFound, newTestErrorResponse(404, "cluster not found")),
+			wantStatus:  http.StatusNotFound,
+			wantKind:    apiErrorNotFound,
+			wantMessage: "cluster not found",
+		},
+		{
+			name:       "typed forbidden reply",
+			err:        project.NewGetClusterV2Forbidden(),
+			wantStatus: http.StatusForbidden,
+			wantKind:   apiErrorForbidden,
+		},
+		{
+			name:        "conflict",
+			err:         newTestGetClusterDefault(http.StatusConflict, newTestErrorResponse(409, "already exists")),
+			wantStatus:  http.StatusConflict,
+			wantKind:    apiErrorConflict,
+			wantMessage: "already exists",
+		},
+		{
+			name:        "object modified",
+			err:         newTestGetClusterDefault(http.StatusInternalServerError, newTestErrorResponse(500, "Operation cannot be fulfilled: the object has been modified; please apply your changes to the latest version")),
+			wantStatus:  http.StatusInternalServerError,
+			wantKind:    apiErrorConflict,
+			wantMessage: "Operation cannot be fulfilled: the object has been modified; please apply your changes to the latest version",
+		},
+		{
+			name:        "webhook not ready",
+			err:         newTestGetClusterDefault(http.StatusInternalServerError, newTestErrorResponse(500, "create failed", `Internal error occurred: failed calling webhook "machine.validation"`)),
+			wantStatus:  http.StatusInternalServerError,
+			wantKind:    apiErrorTransient,
+			wantMessage: "create failed",
+		},
+		{
+			name:        "cluster not ready",
+			err:         newTestGetClusterDefault(http.StatusInternalServerError, newTestErrorResponse(500, "Cluster components are not ready yet")),
+			wantStatus:  http.StatusInternalServerError,
+			wantKind:    apiErrorTransient,
+			wantMessage: "Cluster components are not ready yet",
+		},
+		{
+			name:        "validation",
+			err:         newTestGetClusterDefault(http.StatusBadRequest, newTestErrorResponse(400, "invalid cluster name")),
+			wantStatus:  http.StatusBadRequest,
+			wantKind:    apiErrorValidation,
+			wantMessage: "invalid cluster name",
+		},
+		{
+			name:       "unexpected reply",
+			err:        runtime.NewAPIError("getClusterV2", nil, http.StatusServiceUnavailable),
+			wantStatus: http.StatusServiceUnavailable,
+			wantKind:   apiErrorTransient,
+		},
+		{
+			name:     "no reply",
+			err:      errors.New("connection refused"),
+			wantKind: apiErrorUnknown,
+		},
+		{
+			name:        "wrapped",
+			err:         fmt.Errorf("get cluster: %w", newAPIError(newTestGetClusterDefault(http.StatusNotFound, newTestErrorResponse(404, "cluster not found")))),
+			wantStatus:  http.StatusNotFound,
+			wantKind:    apiErrorNotFound,
+			wantMessage: "cluster not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newAPIError(tc.err)
+			if e.status != tc.wantStatus {
+				t.Errorf("want status %d, got %d", tc.wantStatus, e.status)
+			}
+			if e.kind != tc.wantKind {
+				t.Errorf("want kind %s, got %s", tc.wantKind, e.kind)
+			}
+			if e.message != tc.wantMessage {
+				t.Errorf("want message %q, got %q", tc.wantMessage, e.message)
+			}
+		})
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	err := newAPIError(newTestGetClusterDefault(http.StatusBadRequest, newTestErrorResponse(400, "invalid spec", "spec.version: unsupported")))
+	if want := "invalid spec [spec.version: unsupported]"; err.Error() != want {
+		t.Fatalf("want %q, got %q", want, err.Error())
+	}
+	plain := errors.New("connection refused")
+	if got := newAPIError(plain).Error(); got != plain.Error() {
+		t.Fatalf("want %q, got %q", plain.Error(), got)
+	}
+	if !errors.Is(newAPIError(plain), plain) {
+		t.Fatal("expected the original error to be wrapped")
+	}
+}
+
+func TestDiagFromAPIError(t *testing.T) {
+	path := cty.GetAttrPath("spec")
+
+	d := diagFromAPIError(newTestGetClusterDefault(http.StatusBadRequest, newTestErrorResponse(400, "invalid spec", "spec.version: unsupported")), "unable to create cluster", path)
+	if d[0].Summary != "unable to create cluster: invalid spec" {
+		t.Errorf("unexpected summary: %s", d[0].Summary)
+	}
+	if d[0].Detail != "spec.version: unsupported" {
+		t.Errorf("unexpected detail: %s", d[0].Detail)
+	}
+	if !d[0].AttributePath.Equals(path) {
+		t.Errorf("validation error should be attributed to %v, got %v", path, d[0].AttributePath)
+	}
+
+	d = diagFromAPIError(newTestGetClusterDefault(http.StatusInternalServerError, newTestErrorResponse(500, "internal error")), "unable to create cluster", path)
+	if d[0].AttributePath != nil {
+		t.Errorf("want no attribute path for server errors, got %v", d[0].AttributePath)
+	}
+}
+
+func TestAPIErrorRetryError(t *testing.T) {
+	transient := newAPIError(newTestGetClusterDefault(http.StatusInternalServerError, newTestErrorResponse(500, "Cluster components are not ready yet")))
+	if !transient.retryError().Retryable {
+		t.Error("expected transient error to be retried")
+	}
+	validation := newAPIError(newTestGetClusterDefault(http.StatusBadRequest, newTestErrorResponse(400, "invalid")))
+	if validation.retryError().Retryable {
+		t.Error("expected validation error not to be retried")
+	}
+}
@@ -0,0 +1,119 @@
+package metakube
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/strfmt"
+)
+
+// clientMiddleware wraps the transport of the generated MetaKube client.
+// Unlike http.RoundTripper it sees the operation being submitted, before
+// it is turned into an HTTP request.
+type clientMiddleware func(next runtime.ClientTransport) runtime.ClientTransport
+
+type clientTransportFunc func(*runtime.ClientOperation) (interface{}, error)
+
+func (f clientTransportFunc) Submit(op *runtime.ClientOperation) (interface{}, error) {
+	return f(op)
+}
+
+// operationRequest captures the parameters of a client operation.
+// It implements runtime.ClientRequest, so the generated parameter writers
+// can be used to inspect the operation without sending it.
+type operationRequest struct {
+	method      string
+	pathPattern string
+	pathParams  map[string]string
+	query       url.Values
+	header      http.Header
+	body        interface{}
+}
+
+func newOperationRequest(op *runtime.ClientOperation) (*operationRequest, error) {
+	r := &operationRequest{
+		method:      op.Method,
+		pathPattern: op.PathPattern,
+		pathParams:  make(map[string]string),
+		query:       make(url.Values),
+		header:      make(http.Header),
+	}
+	if op.Params != nil {
+		if err := op.Params.WriteToRequest(r, strfmt.Default); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// path returns the request path with all path parameters filled in.
+func (r *operationRequest) path() string {
+	p := r.pathPattern
+	for k, v := range r.pathParams {
+		p = strings.ReplaceAll(p, "{"+k+"}", url.PathEscape(v))
+	}
+	return p
+}
+
+func (r *operationRequest) SetHeaderParam(name string, values ...string) error {
+	r.header[http.CanonicalHeaderKey(name)] = values
+	return nil
+}
+
+func (r *operationRequest) GetHeaderParams() http.Header {
+	return r.header
+}
+
+func (r *operationRequest) SetQueryParam(name string, values ...string) error {
+	r.query[name] = values
+	return nil
+}
+
+func (r *operationRequest) SetFormParam(_ string, _ ...string) error {
+	return nil
+}
+
+func (r *operationRequest) SetPathParam(name string, value string) error {
+	r.pathParams[name] = value
+	return nil
+}
+
+func (r *operationRequest) GetQueryParams() url.Values {
+	return r.query
+}
+
+func (r *operationRequest) SetFileParam(_ string, _ ...runtime.NamedReadCloser) error {
+	return nil
+}
+
+func (r *operationRequest) SetBodyParam(body interface{}) error {
+	r.body = body
+	return nil
+}
+
+func (r *operationRequest) SetTimeout(_ time.Duration) error {
+	return nil
+}
+
+func (r *operationRequest) GetMethod() string {
+	return r.method
+}
+
+func (r *operationRequest) GetPath() string {
+	return r.path()
+}
+
+func (r *operationRequest) GetBody() []byte {
+	return nil
+}
+
+func (r *operationRequest) GetBodyParam() interface{} {
+	return r.body
+}
+
+func (r *operationRequest) GetFileParam() map[string][]runtime.NamedReadCloser {
+	return nil
+}
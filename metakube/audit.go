@@ -0,0 +1,169 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/mitchellh/go-homedir"
+	"go.uber.org/zap"
+)
+
+const (
+	auditOutcomeSucceeded = "succeeded"
+	auditOutcomeFailed    = "failed"
+)
+
+var auditPathParam = regexp.MustCompile(`{([^}]+)}`)
+
+// auditEntry is the journal record of a mutating API call.
+type auditEntry struct {
+	Time       time.Time       `json:"time"`
+	Operation  string          `json:"operation"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	ProjectID  string          `json:"project_id,omitempty"`
+	ClusterID  string          `json:"cluster_id,omitempty"`
+	ResourceID string          `json:"resource_id,omitempty"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Outcome    string          `json:"outcome"`
+	Status     int             `json:"status,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// auditLog appends a JSON line for every mutating API call to a file. Each
+// line is synced to disk before the result is returned to the resource.
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	log  *zap.SugaredLogger
+	now  func() time.Time
+}
+
+func newAuditLog(path string, log *zap.SugaredLogger) (*auditLog, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{file: f, log: log, now: time.Now}, nil
+}
+
+func (a *auditLog) write(e auditEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *auditLog) middleware(next runtime.ClientTransport) runtime.ClientTransport {
+	return clientTransportFunc(func(op *runtime.ClientOperation) (interface{}, error) {
+		if !isMutatingOperation(op) {
+			return next.Submit(op)
+		}
+
+		e := auditEntry{
+			Time:      a.now().UTC(),
+			Operation: op.ID,
+			Method:    op.Method,
+			Path:      op.PathPattern,
+		}
+		if req, err := newOperationRequest(op); err == nil {
+			e.Path = req.path()
+			e.ProjectID = req.pathParams["project_id"]
+			e.ClusterID = req.pathParams["cluster_id"]
+			// The last path parameter identifies the object changed, unless
+			// it is created in the project or cluster of that parameter.
+			if m := auditPathParam.FindAllStringSubmatch(op.PathPattern, -1); len(m) > 0 {
+				name := m[len(m)-1][1]
+				if op.Method != http.MethodPost || name != "project_id" && name != "cluster_id" {
+					e.ResourceID = req.pathParams[name]
+				}
+			}
+			e.Request = auditBody(req.body)
+		}
+
+		res, err := next.Submit(op)
+		if err != nil {
+			e.Outcome = auditOutcomeFailed
+			e.Status = apiErrorStatus(err)
+			e.Error = newAPIError(err).Error()
+		} else {
+			e.Outcome = auditOutcomeSucceeded
+			if id := payloadID(res); id != "" {
+				e.ResourceID = id
+			}
+		}
+
+		if werr := a.write(e); werr != nil {
+			ctx := op.Context
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			loggerFromContext(ctx, a.log).Errorf("can't write audit log entry for %s: %v", op.ID, werr)
+		}
+		return res, err
+	})
+}
+
+// auditBody returns the request body as JSON with sensitive values redacted.
+func auditBody(body interface{}) json.RawMessage {
+	if body == nil {
+		return nil
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	raw, err = json.Marshal(redactJSON(v))
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// payloadID returns the ID of the object in the reply of a successful
+// operation, if it has one.
+func payloadID(res interface{}) string {
+	v := reflect.ValueOf(res)
+	if !v.IsValid() {
+		return ""
+	}
+	m := v.MethodByName("GetPayload")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return ""
+	}
+	p := m.Call(nil)[0]
+	for p.Kind() == reflect.Ptr || p.Kind() == reflect.Interface {
+		if p.IsNil() {
+			return ""
+		}
+		p = p.Elem()
+	}
+	if p.Kind() != reflect.Struct {
+		return ""
+	}
+	if id := p.FieldByName("ID"); id.IsValid() && id.Kind() == reflect.String {
+		return id.String()
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package metakube
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/syseleven/go-metakube/client/project"
+	"github.com/syseleven/go-metakube/models"
+	"go.uber.org/zap"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLog(path, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	audit.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	client := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id": "c1", "name": "test"}`))
+		case http.MethodPatch:
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error": {"code": 409, "message": "the object has been modified"}}`))
+		default:
+			_, _ = w.Write([]byte(`{"id": "c1", "name": "test"}`))
+		}
+	}), audit.middleware).client
+	ctx := context.Background()
+
+	_, err = client.Project.CreateClusterV2(project.NewCreateClusterV2Params().WithContext(ctx).WithProjectID("p1").WithBody(&models.CreateClusterSpec{
+		Cluster: &models.Cluster{
+			Name: "test",
+			Spec: &models.ClusterSpec{
+				Cloud: &models.CloudSpec{
+					Openstack: &models.OpenstackCloudSpec{Username: "user", Password: "secret"},
+				},
+			},
+		},
+	}), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = client.Project.GetClusterV2(project.NewGetClusterV2Params().WithContext(ctx).WithProjectID("p1").WithClusterID("c1"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = client.Project.PatchClusterV2(project.NewPatchClusterV2Params().WithContext(ctx).WithProjectID("p1").WithClusterID("c1").WithPatch(map[string]interface{}{"name": "renamed"}), nil)
+	if err == nil {
+		t.Fatal("expected conflict error")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []map[string]interface{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line is not JSON: %v", err)
+		}
+		got = append(got, e)
+	}
+
+	want := []map[string]interface{}{
+		{
+			"time":        "2024-01-02T03:04:05Z",
+			"operation":   "createClusterV2",
+			"method":      "POST",
+			"path":        "/api/v2/projects/p1/clusters",
+			"project_id":  "p1",
+			"resource_id": "c1",
+			"outcome":     "succeeded",
+		},
+		{
+			"time":        "2024-01-02T03:04:05Z",
+			"operation":   "patchClusterV2",
+			"method":      "PATCH",
+			"path":        "/api/v2/projects/p1/clusters/c1",
+			"project_id":  "p1",
+			"cluster_id":  "c1",
+			"resource_id": "c1",
+			"request":     map[string]interface{}{"name": "renamed"},
+			"outcome":     "failed",
+			"status":      float64(http.StatusConflict),
+			"error":       "the object has been modified",
+		},
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 entries, got %v", got)
+	}
+	password := got[0]["request"].(map[string]interface{})["cluster"].(map[string]interface{})["spec"].(map[string]interface{})["cloud"].(map[string]interface{})["openstack"].(map[string]interface{})["password"]
+	if password != traceRedacted {
+		t.Fatalf("password was not redacted: %v", password)
+	}
+	delete(got[0], "request")
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
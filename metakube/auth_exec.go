@@ -0,0 +1,152 @@
+package metakube
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	execDefaultAPIVersion = "client.authentication.k8s.io/v1"
+
+	// give up on credential plugins that don't return within this time
+	execTimeout = 2 * time.Minute
+
+	// run the command again this long before tokens actually expire
+	execExpiryDelta = 30 * time.Second
+)
+
+type execConfig struct {
+	command    string
+	args       []string
+	env        map[string]string
+	apiVersion string
+}
+
+func newExecConfig(v []interface{}) execConfig {
+	var ret execConfig
+	if len(v) == 0 || v[0] == nil {
+		return ret
+	}
+	m := v[0].(map[string]interface{})
+	ret.command = m["command"].(string)
+	if args, ok := m["args"].([]interface{}); ok {
+		for _, a := range args {
+			ret.args = append(ret.args, a.(string))
+		}
+	}
+	if env, ok := m["env"].(map[string]interface{}); ok {
+		ret.env = make(map[string]string)
+		for k, v := range env {
+			ret.env[k] = v.(string)
+		}
+	}
+	ret.apiVersion = m["api_version"].(string)
+	if ret.apiVersion == "" {
+		ret.apiVersion = execDefaultAPIVersion
+	}
+	return ret
+}
+
+// execCredential mirrors the ExecCredential object used by kubectl credential plugins.
+type execCredential struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Spec       execCredentialSpec    `json:"spec"`
+	Status     *execCredentialStatus `json:"status,omitempty"`
+}
+
+type execCredentialSpec struct {
+	Interactive bool `json:"interactive"`
+}
+
+type execCredentialStatus struct {
+	Token               string     `json:"token"`
+	ExpirationTimestamp *time.Time `json:"expirationTimestamp,omitempty"`
+}
+
+// execTokenSource runs an external command to obtain a token. The token is
+// cached until it expires or the API rejects it.
+type execTokenSource struct {
+	config execConfig
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func newExecTokenSource(config execConfig) *execTokenSource {
+	return &execTokenSource{config: config}
+}
+
+func (s *execTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && (s.expiry.IsZero() || time.Now().Add(execExpiryDelta).Before(s.expiry)) {
+		return s.token, nil
+	}
+
+	cred, err := s.run(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = cred.Status.Token
+	s.expiry = time.Time{}
+	if cred.Status.ExpirationTimestamp != nil {
+		s.expiry = *cred.Status.ExpirationTimestamp
+	}
+	return s.token, nil
+}
+
+func (s *execTokenSource) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+func (s *execTokenSource) run(ctx context.Context) (*execCredential, error) {
+	info, err := json.Marshal(execCredential{
+		APIVersion: s.config.apiVersion,
+		Kind:       "ExecCredential",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.config.command, s.config.args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(info))
+	for k, v := range s.config.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("exec: running '%s': %v: %s", s.config.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(stdout.Bytes(), &cred); err != nil {
+		return nil, fmt.Errorf("exec: decoding output of '%s': %v", s.config.command, err)
+	}
+	if cred.Kind != "ExecCredential" {
+		return nil, fmt.Errorf("exec: '%s' returned kind '%s', want ExecCredential", s.config.command, cred.Kind)
+	}
+	if cred.APIVersion != s.config.apiVersion {
+		return nil, fmt.Errorf("exec: '%s' returned apiVersion '%s', want '%s'", s.config.command, cred.APIVersion, s.config.apiVersion)
+	}
+	if cred.Status == nil || cred.Status.Token == "" {
+		return nil, fmt.Errorf("exec: '%s' returned no token", s.config.command)
+	}
+	return &cred, nil
+}
@@ -0,0 +1,109 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestExecHelperProcess isn't a real test, it's used as a credential plugin
+// by the tests below.
+func TestExecHelperProcess(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	switch os.Getenv("HELPER_MODE") {
+	case "fail":
+		fmt.Fprint(os.Stderr, "broker unavailable")
+		os.Exit(1)
+	case "invalid":
+		fmt.Print("not json")
+	default:
+		fmt.Printf(`{"apiVersion":"%s","kind":"ExecCredential","status":{"token":"%s","expirationTimestamp":"%s"}}`,
+			execDefaultAPIVersion, os.Getenv("HELPER_TOKEN"), os.Getenv("HELPER_EXPIRY"))
+	}
+	os.Exit(0)
+}
+
+func testExecConfig(env map[string]string) execConfig {
+	env["GO_WANT_HELPER_PROCESS"] = "1"
+	return execConfig{
+		command:    os.Args[0],
+		args:       []string{"-test.run=TestExecHelperProcess"},
+		env:        env,
+		apiVersion: execDefaultAPIVersion,
+	}
+}
+
+func TestExecTokenSource(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	src := newExecTokenSource(testExecConfig(map[string]string{
+		"HELPER_TOKEN":  "broker-token",
+		"HELPER_EXPIRY": expiry,
+	}))
+
+	token, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "broker-token" {
+		t.Fatalf("want broker-token, got %s", token)
+	}
+	if src.expiry.Format(time.RFC3339) != expiry {
+		t.Fatalf("want expiry %s, got %s", expiry, src.expiry)
+	}
+
+	// the cached token is returned without running the command again
+	src.config.env["HELPER_TOKEN"] = "other-token"
+	if token, _ := src.Token(context.Background()); token != "broker-token" {
+		t.Fatalf("want cached broker-token, got %s", token)
+	}
+
+	src.invalidate()
+	if token, _ := src.Token(context.Background()); token != "other-token" {
+		t.Fatalf("want other-token after invalidation, got %s", token)
+	}
+}
+
+func TestExecTokenSourceExpired(t *testing.T) {
+	for _, expiresIn := range []time.Duration{
+		-time.Minute,
+		// tokens expiring within execExpiryDelta are replaced as well
+		10 * time.Second,
+	} {
+		src := newExecTokenSource(testExecConfig(map[string]string{
+			"HELPER_TOKEN":  "broker-token",
+			"HELPER_EXPIRY": time.Now().Add(expiresIn).UTC().Format(time.RFC3339),
+		}))
+		if _, err := src.Token(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		src.config.env["HELPER_TOKEN"] = "other-token"
+		if token, _ := src.Token(context.Background()); token != "other-token" {
+			t.Fatalf("token expiring in %s should be replaced, got %s", expiresIn, token)
+		}
+	}
+}
+
+func TestExecTokenSourceErrors(t *testing.T) {
+	for _, mode := range []string{"fail", "invalid"} {
+		src := newExecTokenSource(testExecConfig(map[string]string{
+			"HELPER_MODE": mode,
+		}))
+		if _, err := src.Token(context.Background()); err == nil {
+			t.Fatalf("%s: expected an error", mode)
+		}
+	}
+
+	config := testExecConfig(map[string]string{
+		"HELPER_TOKEN":  "broker-token",
+		"HELPER_EXPIRY": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	})
+	config.apiVersion = "client.authentication.k8s.io/v1beta1"
+	if _, err := newExecTokenSource(config).Token(context.Background()); err == nil {
+		t.Fatal("expected an error for a mismatching apiVersion")
+	}
+}
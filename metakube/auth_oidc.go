@@ -0,0 +1,360 @@
+package metakube
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// refresh tokens this long before they actually expire
+	oidcExpiryDelta = 30 * time.Second
+
+	oidcDeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var oidcDefaultScopes = []string{"openid", "offline_access", "email"}
+
+type oidcConfig struct {
+	issuerURL    string
+	clientID     string
+	clientSecret string
+	refreshToken string
+	deviceFlow   bool
+	scopes       []string
+}
+
+func newOIDCConfig(v []interface{}) oidcConfig {
+	var ret oidcConfig
+	if len(v) == 0 || v[0] == nil {
+		return ret
+	}
+	m := v[0].(map[string]interface{})
+	ret.issuerURL = m["issuer_url"].(string)
+	ret.clientID = m["client_id"].(string)
+	ret.clientSecret = m["client_secret"].(string)
+	ret.refreshToken = m["refresh_token"].(string)
+	ret.deviceFlow = m["device_flow"].(bool)
+	if scopes, ok := m["scopes"].([]interface{}); ok {
+		for _, s := range scopes {
+			ret.scopes = append(ret.scopes, s.(string))
+		}
+	}
+	if len(ret.scopes) == 0 {
+		ret.scopes = oidcDefaultScopes
+	}
+	return ret
+}
+
+type oidcProviderEndpoints struct {
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+type oidcTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Error        string `json:"error"`
+	ErrorDesc    string `json:"error_description"`
+}
+
+type oidcDeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// oidcTokenSource obtains tokens from an OpenID Connect issuer and keeps them
+// fresh using the refresh token grant. When no usable refresh token is
+// available and device flow is enabled, the device authorization grant is
+// used to obtain a new one.
+type oidcTokenSource struct {
+	config oidcConfig
+	client *http.Client
+	log    *zap.SugaredLogger
+
+	mu           sync.Mutex
+	pending      *oidcTokenFetch
+	endpoints    *oidcProviderEndpoints
+	token        string
+	refreshToken string
+	expiry       time.Time
+}
+
+// oidcTokenFetch is a token request in progress, done is closed once token
+// or err is set.
+type oidcTokenFetch struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+func newOIDCTokenSource(config oidcConfig, client *http.Client, log *zap.SugaredLogger) *oidcTokenSource {
+	return &oidcTokenSource{
+		config:       config,
+		client:       client,
+		log:          log,
+		refreshToken: config.refreshToken,
+	}
+}
+
+func (s *oidcTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.token != "" && (s.expiry.IsZero() || time.Now().Add(oidcExpiryDelta).Before(s.expiry)) {
+		defer s.mu.Unlock()
+		return s.token, nil
+	}
+	// The device flow waits for the user, so the lock is not held while a
+	// token is fetched. Concurrent callers wait for the pending fetch instead
+	// of starting their own.
+	if f := s.pending; f != nil {
+		s.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.token, f.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	f := &oidcTokenFetch{done: make(chan struct{})}
+	s.pending = f
+	s.mu.Unlock()
+
+	r, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	if err == nil {
+		s.update(r)
+		f.token = s.token
+	}
+	f.err = err
+	s.pending = nil
+	s.mu.Unlock()
+	close(f.done)
+	return f.token, f.err
+}
+
+// fetch obtains a new token. It is called by one caller at a time, the one
+// that set pending.
+func (s *oidcTokenSource) fetch(ctx context.Context) (*oidcTokenResponse, error) {
+	if err := s.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	if s.refreshToken != "" {
+		r, err := s.refresh(ctx)
+		if err == nil {
+			return r, nil
+		}
+		if !s.config.deviceFlow {
+			return nil, err
+		}
+		s.log.Debugf("oidc: refresh token rejected, falling back to device flow: %v", err)
+	}
+	if !s.config.deviceFlow {
+		return nil, fmt.Errorf("oidc: no refresh token available and device flow is disabled")
+	}
+	return s.deviceAuthorize(ctx)
+}
+
+func (s *oidcTokenSource) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+func (s *oidcTokenSource) discover(ctx context.Context) error {
+	if s.endpoints != nil {
+		return nil
+	}
+
+	u := strings.TrimSuffix(s.config.issuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("oidc: discovery: %v", err)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: discovery: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: discovery: unexpected status %s", res.Status)
+	}
+
+	var endpoints oidcProviderEndpoints
+	if err := json.NewDecoder(res.Body).Decode(&endpoints); err != nil {
+		return fmt.Errorf("oidc: discovery: %v", err)
+	}
+	if endpoints.TokenEndpoint == "" {
+		return fmt.Errorf("oidc: discovery: issuer '%s' does not advertise a token endpoint", s.config.issuerURL)
+	}
+	s.endpoints = &endpoints
+	return nil
+}
+
+func (s *oidcTokenSource) refresh(ctx context.Context) (*oidcTokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.refreshToken},
+	}
+	r, err := s.requestToken(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	if r.Error != "" {
+		return nil, fmt.Errorf("oidc: refresh token: %s %s", r.Error, r.ErrorDesc)
+	}
+	return r, nil
+}
+
+func (s *oidcTokenSource) deviceAuthorize(ctx context.Context) (*oidcTokenResponse, error) {
+	if s.endpoints.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("oidc: issuer '%s' does not support device flow", s.config.issuerURL)
+	}
+
+	form := url.Values{
+		"client_id": {s.config.clientID},
+		"scope":     {strings.Join(s.config.scopes, " ")},
+	}
+	var auth oidcDeviceAuthorizationResponse
+	if err := s.postForm(ctx, s.endpoints.DeviceAuthorizationEndpoint, form, &auth); err != nil {
+		return nil, fmt.Errorf("oidc: device authorization: %v", err)
+	}
+
+	verificationURI := auth.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = auth.VerificationURI
+	}
+	s.log.Warnf("To authenticate with MetaKube open %s and enter the code %s", verificationURI, auth.UserCode)
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	if auth.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(auth.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	form = url.Values{
+		"grant_type":  {oidcDeviceCodeGrantType},
+		"device_code": {auth.DeviceCode},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("oidc: device authorization was not completed: %v", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		r, err := s.requestToken(ctx, form)
+		if err != nil {
+			return nil, err
+		}
+		switch r.Error {
+		case "":
+			return r, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		default:
+			return nil, fmt.Errorf("oidc: device authorization: %s %s", r.Error, r.ErrorDesc)
+		}
+	}
+}
+
+func (s *oidcTokenSource) requestToken(ctx context.Context, form url.Values) (*oidcTokenResponse, error) {
+	form.Set("client_id", s.config.clientID)
+	if s.config.clientSecret != "" {
+		form.Set("client_secret", s.config.clientSecret)
+	}
+	var r oidcTokenResponse
+	if err := s.postForm(ctx, s.endpoints.TokenEndpoint, form, &r); err != nil {
+		return nil, fmt.Errorf("oidc: token request: %v", err)
+	}
+	if r.Error == "" && r.IDToken == "" && r.AccessToken == "" {
+		return nil, fmt.Errorf("oidc: token request: issuer returned no token")
+	}
+	return &r, nil
+}
+
+// postForm sends a form encoded request and decodes the JSON reply into v.
+// OAuth 2.0 error replies are JSON documents as well, so they are decoded
+// regardless of the status code.
+func (s *oidcTokenSource) postForm(ctx context.Context, endpoint string, form url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unexpected reply (%s): %v", res.Status, err)
+	}
+	return nil
+}
+
+func (s *oidcTokenSource) update(r *oidcTokenResponse) {
+	// MetaKube authenticates users by their ID token, the access token is
+	// used only with issuers that don't return one.
+	s.token = r.IDToken
+	if s.token == "" {
+		s.token = r.AccessToken
+	}
+	if r.RefreshToken != "" {
+		s.refreshToken = r.RefreshToken
+	}
+	s.expiry = time.Time{}
+	if r.ExpiresIn > 0 {
+		s.expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	} else if exp, ok := jwtExpiry(s.token); ok {
+		s.expiry = exp
+	}
+}
+
+// jwtExpiry returns the value of the exp claim of a JWT. The signature is not
+// verified, the token is only inspected to schedule the refresh.
+func jwtExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(raw, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
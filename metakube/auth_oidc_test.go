@@ -0,0 +1,284 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testOIDCIssuer is a minimal stand-in for an OpenID Connect issuer.
+type testOIDCIssuer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	expiresIn     int64
+	refreshTokens map[string]bool
+	tokenRequests int
+	devicePending int
+	// when set, device codes are pending until the channel is closed
+	deviceApproved chan struct{}
+	// receives a value for every device authorization request
+	deviceStarted chan struct{}
+}
+
+func newTestOIDCIssuer(t *testing.T) *testOIDCIssuer {
+	t.Helper()
+	issuer := &testOIDCIssuer{
+		expiresIn:     3600,
+		refreshTokens: map[string]bool{"valid-refresh-token": true},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        issuer.URL,
+			"token_endpoint":                issuer.URL + "/token",
+			"device_authorization_endpoint": issuer.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if issuer.deviceStarted != nil {
+			issuer.deviceStarted <- struct{}{}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": issuer.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		issuer.tokenRequests++
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.PostForm.Get("client_id") != "terraform" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		switch r.PostForm.Get("grant_type") {
+		case "refresh_token":
+			if !issuer.refreshTokens[r.PostForm.Get("refresh_token")] {
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		case oidcDeviceCodeGrantType:
+			approved := true
+			if issuer.deviceApproved != nil {
+				select {
+				case <-issuer.deviceApproved:
+				default:
+					approved = false
+				}
+			}
+			if !approved || issuer.devicePending > 0 {
+				issuer.devicePending--
+				w.WriteHeader(http.StatusBadRequest)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "authorization_pending"})
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+		issuer.refreshTokens["rotated-refresh-token"] = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "access-token",
+			"id_token":      "id-token",
+			"refresh_token": "rotated-refresh-token",
+			"expires_in":    issuer.expiresIn,
+		})
+	})
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+func (i *testOIDCIssuer) requests() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.tokenRequests
+}
+
+func TestOIDCTokenSourceRefreshToken(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	src := newOIDCTokenSource(oidcConfig{
+		issuerURL:    issuer.URL,
+		clientID:     "terraform",
+		refreshToken: "valid-refresh-token",
+	}, issuer.Client(), zap.NewNop().Sugar())
+
+	for i := 0; i < 3; i++ {
+		token, err := src.Token(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "id-token" {
+			t.Fatalf("want id-token, got %s", token)
+		}
+	}
+	if got := issuer.requests(); got != 1 {
+		t.Fatalf("token should be cached, want 1 token request, got %d", got)
+	}
+	if src.refreshToken != "rotated-refresh-token" {
+		t.Fatalf("refresh token was not rotated, got %s", src.refreshToken)
+	}
+}
+
+func TestOIDCTokenSourceRefreshesExpiredToken(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	// tokens expiring within oidcExpiryDelta are refreshed on every call
+	issuer.expiresIn = 1
+	src := newOIDCTokenSource(oidcConfig{
+		issuerURL:    issuer.URL,
+		clientID:     "terraform",
+		refreshToken: "valid-refresh-token",
+	}, issuer.Client(), zap.NewNop().Sugar())
+
+	for i := 0; i < 2; i++ {
+		if _, err := src.Token(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := issuer.requests(); got != 2 {
+		t.Fatalf("want 2 token requests, got %d", got)
+	}
+}
+
+func TestOIDCTokenSourceInvalidRefreshToken(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	src := newOIDCTokenSource(oidcConfig{
+		issuerURL:    issuer.URL,
+		clientID:     "terraform",
+		refreshToken: "revoked-refresh-token",
+	}, issuer.Client(), zap.NewNop().Sugar())
+
+	if _, err := src.Token(context.Background()); err == nil {
+		t.Fatal("expected error for an invalid refresh token")
+	}
+}
+
+func TestOIDCTokenSourceDeviceFlow(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	issuer.devicePending = 1
+	src := newOIDCTokenSource(oidcConfig{
+		issuerURL:    issuer.URL,
+		clientID:     "terraform",
+		refreshToken: "revoked-refresh-token",
+		deviceFlow:   true,
+		scopes:       oidcDefaultScopes,
+	}, issuer.Client(), zap.NewNop().Sugar())
+
+	token, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "id-token" {
+		t.Fatalf("want id-token, got %s", token)
+	}
+	if src.refreshToken != "rotated-refresh-token" {
+		t.Fatalf("refresh token from device flow was not stored, got %s", src.refreshToken)
+	}
+}
+
+func TestOIDCTokenSourceDeviceFlowDoesNotBlock(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	issuer.deviceApproved = make(chan struct{})
+	issuer.deviceStarted = make(chan struct{}, 2)
+	src := newOIDCTokenSource(oidcConfig{
+		issuerURL:  issuer.URL,
+		clientID:   "terraform",
+		deviceFlow: true,
+		scopes:     oidcDefaultScopes,
+	}, issuer.Client(), zap.NewNop().Sugar())
+
+	type result struct {
+		token string
+		err   error
+	}
+	results := make(chan result, 2)
+	fetch := func() {
+		token, err := src.Token(context.Background())
+		results <- result{token, err}
+	}
+	go fetch()
+	<-issuer.deviceStarted
+
+	// invalidating and requesting a token must not wait for the user
+	invalidated := make(chan struct{})
+	go func() {
+		src.invalidate()
+		close(invalidated)
+	}()
+	select {
+	case <-invalidated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("invalidate blocked while waiting for the device authorization")
+	}
+	go fetch()
+
+	close(issuer.deviceApproved)
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil {
+			t.Fatalf("unexpected error: %v", r.err)
+		}
+		if r.token != "id-token" {
+			t.Fatalf("want id-token, got %s", r.token)
+		}
+	}
+	if got := len(issuer.deviceStarted); got != 0 {
+		t.Fatalf("want a single device authorization, got %d more", got)
+	}
+}
+
+func TestOIDCTokenSourceFromConfigUsesBaseTransport(t *testing.T) {
+	issuer := newTestOIDCIssuer(t)
+	var requests int32
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&requests, 1)
+		return issuer.Client().Transport.RoundTrip(r)
+	})
+	src, diagnostics := newOIDCTokenSourceFromConfig(oidcConfig{
+		issuerURL:    issuer.URL,
+		clientID:     "terraform",
+		refreshToken: "valid-refresh-token",
+	}, base, zap.NewNop().Sugar())
+	if diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", diagnostics)
+	}
+
+	if _, err := src.Token(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got == 0 {
+		t.Fatal("issuer should be requested through the base transport")
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	// {"alg":"none"}.{"exp":1700000000}.
+	exp, ok := jwtExpiry("eyJhbGciOiJub25lIn0.eyJleHAiOjE3MDAwMDAwMDB9.sig")
+	if !ok {
+		t.Fatal("expected exp claim to be parsed")
+	}
+	if exp.Unix() != 1700000000 {
+		t.Fatalf("want 1700000000, got %d", exp.Unix())
+	}
+	if _, ok := jwtExpiry("not-a-jwt"); ok {
+		t.Fatal("expected opaque token to have no expiry")
+	}
+}
@@ -50,7 +50,7 @@ func dataSourceMetakubeK8sClusterVersionRead(ctx context.Context, d *schema.Reso
 	p := versions.NewGetMasterVersionsParams().WithContext(ctx)
 	r, err := k.client.Versions.GetMasterVersions(p, k.auth)
 	if err != nil {
-		return diag.Errorf("%s", stringifyResponseError(err))
+		return diagFromAPIError(err, "Can't list versions", nil)
 	}
 
 	var all []string
@@ -0,0 +1,209 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+)
+
+const (
+	kubeconfigModeAdmin     = "admin"
+	kubeconfigModeOIDC      = "oidc"
+	kubeconfigModeKubelogin = "kubelogin"
+)
+
+func dataSourceMetakubeKubeconfig() *schema.Resource {
+	s := metakubeDataSourceKubeconfigClusterFields()
+	s["project_id"].Computed = true
+	s["exec_user"] = &schema.Schema{
+		Type:        schema.TypeBool,
+		Optional:    true,
+		Description: "Replace the users by a credential plugin returning the provider token",
+	}
+	s["merge"] = &schema.Schema{
+		Type:        schema.TypeList,
+		Optional:    true,
+		Description: "Further clusters added as contexts",
+		Elem: &schema.Resource{
+			Schema: metakubeDataSourceKubeconfigClusterFields(),
+		},
+	}
+	s["kube_config"] = &schema.Schema{
+		Type:        schema.TypeString,
+		Computed:    true,
+		Sensitive:   true,
+		Description: "Kubeconfig content",
+	}
+	return &schema.Resource{
+		ReadContext: metakubeDataSourceKubeconfigRead,
+		Schema:      s,
+	}
+}
+
+func metakubeDataSourceKubeconfigClusterFields() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"project_id": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.NoZeroValues,
+			Description:  "Project of the cluster, defaults to the provider project",
+		},
+		"cluster_id": {
+			Type:         schema.TypeString,
+			Required:     true,
+			ValidateFunc: validation.NoZeroValues,
+			Description:  "Cluster identifier",
+		},
+		"mode": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      kubeconfigModeAdmin,
+			ValidateFunc: validation.StringInSlice([]string{kubeconfigModeAdmin, kubeconfigModeOIDC, kubeconfigModeKubelogin}, false),
+			Description:  "Credentials of the kubeconfig, one of admin, oidc or kubelogin",
+		},
+		"context_name": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.NoZeroValues,
+			Description:  "Name of the context",
+		},
+		"cluster_name": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.NoZeroValues,
+			Description:  "Name of the cluster",
+		},
+		"user_name": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.NoZeroValues,
+			Description:  "Name of the user",
+		},
+		"namespace": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.NoZeroValues,
+			Description:  "Default namespace of the context",
+		},
+	}
+}
+
+func metakubeDataSourceKubeconfigRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+	k := m.(*metakubeProviderMeta)
+
+	var exec *execConfig
+	if d.Get("exec_user").(bool) {
+		if k.kubeconfigExec == nil {
+			return diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "The provider token can't be used by a credential plugin",
+				Detail:        fmt.Sprintf("%v. Configure the provider with token_path or an exec credential plugin.", k.kubeconfigExecErr),
+				AttributePath: cty.GetAttrPath("exec_user"),
+			}}
+		}
+		exec = k.kubeconfigExec
+	}
+
+	entries := []map[string]interface{}{{
+		"project_id":   d.Get("project_id"),
+		"cluster_id":   d.Get("cluster_id"),
+		"mode":         d.Get("mode"),
+		"context_name": d.Get("context_name"),
+		"cluster_name": d.Get("cluster_name"),
+		"user_name":    d.Get("user_name"),
+		"namespace":    d.Get("namespace"),
+	}}
+	for _, v := range d.Get("merge").([]interface{}) {
+		entries = append(entries, v.(map[string]interface{}))
+	}
+
+	var (
+		ret *kubeconfig
+		ids []string
+	)
+	for i, e := range entries {
+		path := cty.Path{}
+		if i > 0 {
+			path = cty.GetAttrPath("merge").IndexInt(i - 1)
+		}
+		projectID := e["project_id"].(string)
+		if projectID == "" {
+			var err error
+			if projectID, err = k.projectID(ctx, ""); err != nil {
+				return diag.Diagnostics{{
+					Severity:      diag.Error,
+					Summary:       err.Error(),
+					AttributePath: path.GetAttr("project_id"),
+				}}
+			}
+		}
+		if i == 0 {
+			_ = d.Set("project_id", projectID)
+		}
+		clusterID := e["cluster_id"].(string)
+		ids = append(ids, clusterID)
+
+		c, err := metakubeClusterKubeconfig(ctx, k, projectID, clusterID, e["mode"].(string), kubeconfigOptions{
+			contextName: e["context_name"].(string),
+			clusterName: e["cluster_name"].(string),
+			userName:    e["user_name"].(string),
+			namespace:   e["namespace"].(string),
+			exec:        exec,
+		})
+		if err != nil {
+			return diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't get kubeconfig of cluster '%s': %v", clusterID, err),
+				AttributePath: path.GetAttr("cluster_id"),
+			}}
+		}
+		if ret == nil {
+			ret = c
+		} else if err := ret.merge(c); err != nil {
+			return diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't merge kubeconfig of cluster '%s': %v", clusterID, err),
+				Detail:        "Set context_name, cluster_name and user_name to unique names.",
+				AttributePath: path,
+			}}
+		}
+	}
+
+	raw, err := ret.marshal()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(strings.Join(ids, ","))
+	_ = d.Set("kube_config", raw)
+	return nil
+}
+
+// metakubeClusterKubeconfig returns the current context of the kubeconfig of
+// the cluster for the mode, changed by the options.
+func metakubeClusterKubeconfig(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID, mode string, o kubeconfigOptions) (*kubeconfig, error) {
+	var (
+		raw string
+		err error
+	)
+	switch mode {
+	case kubeconfigModeOIDC:
+		raw, err = metakubeClusterUpdateOIDCKubeconfig(ctx, k, projectID, clusterID)
+	case kubeconfigModeKubelogin:
+		raw, err = metakubeClusterUpdateKubeloginKubeconfig(ctx, k, projectID, clusterID)
+	default:
+		raw, err = metakubeClusterUpdateKubeconfig(ctx, k, projectID, clusterID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseKubeconfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	return c.extractCurrent(o)
+}
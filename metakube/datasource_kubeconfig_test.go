@@ -0,0 +1,112 @@
+package metakube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func newTestKubeconfigMeta(t *testing.T) *metakubeProviderMeta {
+	t.Helper()
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		switch r.URL.Path {
+		case "/api/v2/projects/p1/clusters/c1/kubeconfig":
+			_, _ = w.Write([]byte(testKubeconfig))
+		case "/api/v2/projects/p1/clusters/c2/kubeconfig":
+			_, _ = w.Write([]byte(strings.ReplaceAll(testKubeconfig, "c1", "c2")))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	k.defaultProjectID = "p1"
+	k.kubeconfigExec = &execConfig{command: "metakube-token", apiVersion: execDefaultAPIVersion}
+	return k
+}
+
+func TestMetakubeDataSourceKubeconfigRead(t *testing.T) {
+	k := newTestKubeconfigMeta(t)
+	d := schema.TestResourceDataRaw(t, dataSourceMetakubeKubeconfig().Schema, map[string]interface{}{
+		"cluster_id":   "c1",
+		"context_name": "staging",
+		"namespace":    "apps",
+		"exec_user":    true,
+		"merge": []interface{}{
+			map[string]interface{}{
+				"cluster_id":   "c2",
+				"context_name": "production",
+				"user_name":    "production",
+			},
+		},
+	})
+
+	if diagnostics := metakubeDataSourceKubeconfigRead(context.Background(), d, k); diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", diagnostics)
+	}
+	if d.Id() != "c1,c2" || d.Get("project_id") != "p1" {
+		t.Fatalf("unexpected id %q or project %q", d.Id(), d.Get("project_id"))
+	}
+	c, err := parseKubeconfig(d.Get("kube_config").(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := map[string]interface{}{
+		"apiVersion":      execDefaultAPIVersion,
+		"command":         "metakube-token",
+		"interactiveMode": "IfAvailable",
+	}
+	want := &kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []kubeconfigNamedCluster{
+			{Name: "c1", Cluster: kubeconfigCluster{Server: "https://c1.metakube.example.com:6443", CertificateAuthorityData: "Q0EgUEVN"}},
+			{Name: "c2", Cluster: kubeconfigCluster{Server: "https://c2.metakube.example.com:6443", CertificateAuthorityData: "Q0EgUEVN"}},
+		},
+		Contexts: []kubeconfigNamedContext{
+			{Name: "staging", Context: kubeconfigContext{Cluster: "c1", User: "admin", Namespace: "apps"}},
+			{Name: "production", Context: kubeconfigContext{Cluster: "c2", User: "production"}},
+		},
+		Users: []kubeconfigNamedUser{
+			{Name: "admin", User: kubeconfigUser{Extra: map[string]interface{}{"exec": exec}}},
+			{Name: "production", User: kubeconfigUser{Extra: map[string]interface{}{"exec": exec}}},
+		},
+		CurrentContext: "staging",
+	}
+	if diff := cmp.Diff(want, c); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMetakubeDataSourceKubeconfigReadDuplicateNames(t *testing.T) {
+	k := newTestKubeconfigMeta(t)
+	d := schema.TestResourceDataRaw(t, dataSourceMetakubeKubeconfig().Schema, map[string]interface{}{
+		"cluster_id": "c1",
+		"merge": []interface{}{
+			map[string]interface{}{"cluster_id": "c2"},
+		},
+	})
+
+	diagnostics := metakubeDataSourceKubeconfigRead(context.Background(), d, k)
+	if !diagnostics.HasError() || !strings.Contains(diagnostics[0].Summary, "duplicate context name 'default'") {
+		t.Fatalf("want duplicate name error, got %v", diagnostics)
+	}
+}
+
+func TestMetakubeDataSourceKubeconfigReadNoExec(t *testing.T) {
+	k := newTestKubeconfigMeta(t)
+	k.kubeconfigExec, k.kubeconfigExecErr = nil, errors.New("a static token is only known to the provider")
+	d := schema.TestResourceDataRaw(t, dataSourceMetakubeKubeconfig().Schema, map[string]interface{}{
+		"cluster_id": "c1",
+		"exec_user":  true,
+	})
+
+	diagnostics := metakubeDataSourceKubeconfigRead(context.Background(), d, k)
+	if !diagnostics.HasError() || !strings.Contains(diagnostics[0].Detail, "static token") {
+		t.Fatalf("want error telling why there is no credential plugin, got %v", diagnostics)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
-	"github.com/syseleven/go-metakube/client/project"
 )
 
 func dataSourceMetakubeProject() *schema.Resource {
@@ -29,26 +28,11 @@ func dataSourceMetakubeProject() *schema.Resource {
 func metakubeDataSourceProjectRead(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	meta := m.(*metakubeProviderMeta)
 
-	p := project.NewListProjectsParams().WithContext(ctx)
-	res, err := meta.client.Project.ListProjects(p, meta.auth)
+	id, err := metakubeProjectIDByName(ctx, meta, d.Get("name").(string))
 	if err != nil {
 		return diag.FromErr(err)
 	}
-
-	name := d.Get("name").(string)
-	matches := 0
-	for _, r := range res.Payload {
-		if r != nil && r.Name == name {
-			d.SetId(r.ID)
-			matches++
-		}
-	}
-
-	if matches == 0 {
-		return diag.Errorf("Could not find a project with name: %s", name)
-	} else if matches > 1 {
-		return diag.Errorf("Found multiple projects with name: %s", name)
-	}
+	d.SetId(id)
 
 	return nil
 }
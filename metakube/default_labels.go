@@ -0,0 +1,136 @@
+package metakube
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func newDefaultLabels(v []interface{}) map[string]string {
+	if len(v) == 0 || v[0] == nil {
+		return nil
+	}
+	m, _ := v[0].(map[string]interface{})["labels"].(map[string]interface{})
+	if len(m) == 0 {
+		return nil
+	}
+	ret := make(map[string]string, len(m))
+	for key, val := range m {
+		ret[key] = val.(string)
+	}
+	return ret
+}
+
+// mergeDefaultLabels returns the provider default labels overridden by the
+// labels set on the resource.
+func mergeDefaultLabels(defaults, labels map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return labels
+	}
+	ret := make(map[string]string, len(defaults)+len(labels))
+	for key, val := range defaults {
+		ret[key] = val
+	}
+	for key, val := range labels {
+		ret[key] = val
+	}
+	return ret
+}
+
+// labelsWithoutDefaults returns the labels of an object without those that
+// were added from the provider default labels. A label is kept if its value
+// differs from the default or if it is already set on the resource.
+func labelsWithoutDefaults(labels map[string]string, current interface{}, defaults map[string]string) map[string]string {
+	if len(defaults) == 0 || len(labels) == 0 {
+		return labels
+	}
+	set, _ := current.(map[string]interface{})
+	ret := make(map[string]string, len(labels))
+	for key, val := range labels {
+		if v, ok := defaults[key]; ok && v == val {
+			if _, ok := set[key]; !ok {
+				continue
+			}
+		}
+		ret[key] = val
+	}
+	return ret
+}
+
+// labelsAll returns all labels of an object from the labels set on the
+// resource and the provider default labels. System labels added by MetaKube
+// are kept from the current labels.
+func labelsAll(current, labels interface{}, defaults map[string]string) map[string]interface{} {
+	merged := make(map[string]string)
+	if m, ok := current.(map[string]interface{}); ok {
+		for key, val := range m {
+			if metakubeResourceSystemLabelOrTag(key) {
+				merged[key] = val.(string)
+			}
+		}
+	}
+	if m, ok := labels.(map[string]interface{}); ok {
+		for key, val := range m {
+			merged[key] = val.(string)
+		}
+	}
+	merged = mergeDefaultLabels(defaults, merged)
+
+	ret := make(map[string]interface{}, len(merged))
+	for key, val := range merged {
+		ret[key] = val
+	}
+	return ret
+}
+
+// labelsAllDiff plans labels_all from the labels at path.
+func labelsAllDiff(path string) schema.CustomizeDiffFunc {
+	return func(_ context.Context, d *schema.ResourceDiff, m interface{}) error {
+		if !d.NewValueKnown(path) {
+			return d.SetNewComputed("labels_all")
+		}
+
+		var defaults map[string]string
+		if k, ok := m.(*metakubeProviderMeta); ok {
+			defaults = k.defaultLabels
+		}
+
+		old, _ := d.Get("labels_all").(map[string]interface{})
+		all := labelsAll(old, d.Get(path), defaults)
+		if reflect.DeepEqual(old, all) || len(old) == 0 && len(all) == 0 {
+			return nil
+		}
+		return d.SetNew("labels_all", all)
+	}
+}
+
+// labelsAllBefore returns the labels of an object before the change. The
+// labels at path are included for state written before labels_all existed.
+func labelsAllBefore(d *schema.ResourceData, path string) map[string]interface{} {
+	ret := make(map[string]interface{})
+	for _, key := range []string{path, "labels_all"} {
+		old, _ := d.GetChange(key)
+		if m, ok := old.(map[string]interface{}); ok {
+			for k, v := range m {
+				ret[k] = v
+			}
+		}
+	}
+	return ret
+}
+
+// labelsPatch returns the labels to send in a merge patch to change the
+// object labels from old to new, removed labels are set to null.
+func labelsPatch(oldMap, newMap map[string]interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(newMap))
+	for key, val := range newMap {
+		ret[key] = val
+	}
+	for key := range oldMap {
+		if _, ok := newMap[key]; !ok {
+			ret[key] = nil
+		}
+	}
+	return ret
+}
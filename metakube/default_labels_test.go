@@ -0,0 +1,155 @@
+package metakube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestMergeDefaultLabels(t *testing.T) {
+	defaults := map[string]string{"team": "platform", "environment": "dev"}
+	got := mergeDefaultLabels(defaults, map[string]string{"environment": "prod", "app": "web"})
+	want := map[string]string{"team": "platform", "environment": "prod", "app": "web"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if got := mergeDefaultLabels(nil, map[string]string{"app": "web"}); len(got) != 1 {
+		t.Fatalf("want labels unchanged without defaults, got %v", got)
+	}
+}
+
+func TestLabelsWithoutDefaults(t *testing.T) {
+	defaults := map[string]string{"team": "platform", "environment": "dev"}
+	testCases := []struct {
+		name    string
+		labels  map[string]string
+		current interface{}
+		want    map[string]string
+	}{
+		{
+			name:   "defaults are removed",
+			labels: map[string]string{"team": "platform", "environment": "dev", "app": "web"},
+			want:   map[string]string{"app": "web"},
+		},
+		{
+			name:   "overridden defaults are kept",
+			labels: map[string]string{"team": "platform", "environment": "prod"},
+			want:   map[string]string{"environment": "prod"},
+		},
+		{
+			name:    "defaults set on the resource are kept",
+			labels:  map[string]string{"team": "platform", "environment": "dev"},
+			current: map[string]interface{}{"team": "platform"},
+			want:    map[string]string{"team": "platform"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := labelsWithoutDefaults(tc.labels, tc.current, defaults)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLabelsAllDiff(t *testing.T) {
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"labels": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"labels_all": {
+				Type:     schema.TypeMap,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+		CustomizeDiff: labelsAllDiff("labels"),
+	}
+	k := &metakubeProviderMeta{defaultLabels: map[string]string{"team": "platform", "environment": "dev"}}
+
+	testCases := []struct {
+		name   string
+		state  map[string]string
+		config map[string]interface{}
+		want   map[string]string
+	}{
+		{
+			name:   "create",
+			config: map[string]interface{}{"environment": "prod"},
+			want: map[string]string{
+				"labels.%":               "1",
+				"labels.environment":     "prod",
+				"labels_all.%":           "2",
+				"labels_all.environment": "prod",
+				"labels_all.team":        "platform",
+			},
+		},
+		{
+			name: "no changes",
+			state: map[string]string{
+				"labels_all.%":              "3",
+				"labels_all.environment":    "dev",
+				"labels_all.team":           "platform",
+				"labels_all.system/project": "p1",
+			},
+			config: map[string]interface{}{},
+		},
+		{
+			name: "default labels changed",
+			state: map[string]string{
+				"labels_all.%":              "2",
+				"labels_all.team":           "infra",
+				"labels_all.system/project": "p1",
+			},
+			config: map[string]interface{}{},
+			want: map[string]string{
+				"labels_all.%":           "3",
+				"labels_all.environment": "dev",
+				"labels_all.team":        "platform",
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := &terraform.InstanceState{ID: "id", Attributes: tc.state}
+			if tc.state == nil {
+				state = nil
+			}
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{"labels": tc.config})
+			d, err := r.Diff(context.Background(), state, config, k)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make(map[string]string)
+			if d != nil {
+				for key, attr := range d.Attributes {
+					if attr.Old != attr.New {
+						got[key] = attr.New
+					}
+				}
+			}
+			if tc.want == nil {
+				tc.want = map[string]string{}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLabelsPatch(t *testing.T) {
+	old := map[string]interface{}{"team": "platform", "environment": "dev"}
+	got := labelsPatch(old, map[string]interface{}{"team": "infra"})
+	want := map[string]interface{}{"team": "infra", "environment": nil}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,363 @@
+package metakube
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v3"
+)
+
+// KubeconfigTokenFileCommand is the argument that makes the provider binary
+// a credential plugin printing the token of a token file, see
+// RunKubeconfigTokenFile.
+const KubeconfigTokenFileCommand = "kubeconfig-token"
+
+// kubeconfig is the subset of the kubeconfig file format the provider reads
+// and writes, fields it does not know are kept in Extra.
+type kubeconfig struct {
+	APIVersion     string                   `yaml:"apiVersion,omitempty"`
+	Kind           string                   `yaml:"kind,omitempty"`
+	Clusters       []kubeconfigNamedCluster `yaml:"clusters"`
+	Contexts       []kubeconfigNamedContext `yaml:"contexts"`
+	Users          []kubeconfigNamedUser    `yaml:"users"`
+	CurrentContext string                   `yaml:"current-context"`
+	Extra          map[string]interface{}   `yaml:",inline"`
+}
+
+type kubeconfigNamedCluster struct {
+	Name    string            `yaml:"name"`
+	Cluster kubeconfigCluster `yaml:"cluster"`
+}
+
+type kubeconfigCluster struct {
+	Server                   string                 `yaml:"server"`
+	CertificateAuthorityData string                 `yaml:"certificate-authority-data,omitempty"`
+	Extra                    map[string]interface{} `yaml:",inline"`
+}
+
+type kubeconfigNamedContext struct {
+	Name    string            `yaml:"name"`
+	Context kubeconfigContext `yaml:"context"`
+}
+
+type kubeconfigContext struct {
+	Cluster   string                 `yaml:"cluster"`
+	User      string                 `yaml:"user"`
+	Namespace string                 `yaml:"namespace,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline"`
+}
+
+type kubeconfigNamedUser struct {
+	Name string         `yaml:"name"`
+	User kubeconfigUser `yaml:"user"`
+}
+
+type kubeconfigUser struct {
+	ClientCertificateData string                 `yaml:"client-certificate-data,omitempty"`
+	ClientKeyData         string                 `yaml:"client-key-data,omitempty"`
+	Token                 string                 `yaml:"token,omitempty"`
+	Extra                 map[string]interface{} `yaml:",inline"`
+}
+
+// kubeconfigAttributes are the connection settings of the current context of
+// a kubeconfig, certificates and keys are PEM encoded.
+type kubeconfigAttributes struct {
+	host                 string
+	clusterCACertificate string
+	clientCertificate    string
+	clientKey            string
+	token                string
+	contextName          string
+	clusterName          string
+	userName             string
+}
+
+func parseKubeconfig(raw string) (*kubeconfig, error) {
+	var ret kubeconfig
+	if err := yaml.Unmarshal([]byte(raw), &ret); err != nil {
+		return nil, fmt.Errorf("can't parse kubeconfig: %w", err)
+	}
+	return &ret, nil
+}
+
+// currentContext returns the current context, the first context if none is
+// set.
+func (c *kubeconfig) currentContext() (*kubeconfigNamedContext, error) {
+	if len(c.Contexts) == 0 {
+		return nil, fmt.Errorf("kubeconfig has no contexts")
+	}
+	if c.CurrentContext == "" {
+		return &c.Contexts[0], nil
+	}
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == c.CurrentContext {
+			return &c.Contexts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("current context '%s' not found in kubeconfig", c.CurrentContext)
+}
+
+func (c *kubeconfig) cluster(name string) (*kubeconfigNamedCluster, error) {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			return &c.Clusters[i], nil
+		}
+	}
+	return nil, fmt.Errorf("cluster '%s' not found in kubeconfig", name)
+}
+
+func (c *kubeconfig) user(name string) (*kubeconfigNamedUser, error) {
+	for i := range c.Users {
+		if c.Users[i].Name == name {
+			return &c.Users[i], nil
+		}
+	}
+	return nil, fmt.Errorf("user '%s' not found in kubeconfig", name)
+}
+
+// attributes returns the connection settings of the current context.
+func (c *kubeconfig) attributes() (*kubeconfigAttributes, error) {
+	ctx, err := c.currentContext()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := c.cluster(ctx.Context.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	ret := &kubeconfigAttributes{
+		host:        cluster.Cluster.Server,
+		contextName: ctx.Name,
+		clusterName: cluster.Name,
+		userName:    ctx.Context.User,
+	}
+	if ret.clusterCACertificate, err = decodeKubeconfigData(cluster.Cluster.CertificateAuthorityData); err != nil {
+		return nil, fmt.Errorf("invalid certificate-authority-data of cluster '%s': %w", cluster.Name, err)
+	}
+	if ctx.Context.User == "" {
+		return ret, nil
+	}
+	user, err := c.user(ctx.Context.User)
+	if err != nil {
+		return nil, err
+	}
+	if ret.clientCertificate, err = decodeKubeconfigData(user.User.ClientCertificateData); err != nil {
+		return nil, fmt.Errorf("invalid client-certificate-data of user '%s': %w", user.Name, err)
+	}
+	if ret.clientKey, err = decodeKubeconfigData(user.User.ClientKeyData); err != nil {
+		return nil, fmt.Errorf("invalid client-key-data of user '%s': %w", user.Name, err)
+	}
+	ret.token = user.User.Token
+	return ret, nil
+}
+
+// kubeconfigOptions change the context taken from a kubeconfig.
+type kubeconfigOptions struct {
+	contextName string
+	clusterName string
+	userName    string
+	namespace   string
+	// if set, the user gets its token from this credential plugin
+	exec *execConfig
+}
+
+// extractCurrent returns a kubeconfig with only the current context, its
+// cluster and user, changed by the options.
+func (c *kubeconfig) extractCurrent(o kubeconfigOptions) (*kubeconfig, error) {
+	ctx, err := c.currentContext()
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := c.cluster(ctx.Context.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	user, err := c.user(ctx.Context.User)
+	if err != nil {
+		return nil, err
+	}
+	newCtx, newCluster, newUser := *ctx, *cluster, *user
+	if o.contextName != "" {
+		newCtx.Name = o.contextName
+	}
+	if o.clusterName != "" {
+		newCluster.Name = o.clusterName
+		newCtx.Context.Cluster = o.clusterName
+	}
+	if o.userName != "" {
+		newUser.Name = o.userName
+		newCtx.Context.User = o.userName
+	}
+	if o.namespace != "" {
+		newCtx.Context.Namespace = o.namespace
+	}
+	if o.exec != nil {
+		newUser.User = kubeconfigExecUser(o.exec)
+	}
+	return &kubeconfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Clusters:       []kubeconfigNamedCluster{newCluster},
+		Contexts:       []kubeconfigNamedContext{newCtx},
+		Users:          []kubeconfigNamedUser{newUser},
+		CurrentContext: newCtx.Name,
+	}, nil
+}
+
+// merge adds the contexts, clusters and users of other, which must not reuse
+// their names.
+func (c *kubeconfig) merge(other *kubeconfig) error {
+	for _, ctx := range other.Contexts {
+		if _, err := c.context(ctx.Name); err == nil {
+			return fmt.Errorf("duplicate context name '%s'", ctx.Name)
+		}
+	}
+	for _, cluster := range other.Clusters {
+		if _, err := c.cluster(cluster.Name); err == nil {
+			return fmt.Errorf("duplicate cluster name '%s'", cluster.Name)
+		}
+	}
+	for _, user := range other.Users {
+		if _, err := c.user(user.Name); err == nil {
+			return fmt.Errorf("duplicate user name '%s'", user.Name)
+		}
+	}
+	c.Contexts = append(c.Contexts, other.Contexts...)
+	c.Clusters = append(c.Clusters, other.Clusters...)
+	c.Users = append(c.Users, other.Users...)
+	return nil
+}
+
+func (c *kubeconfig) context(name string) (*kubeconfigNamedContext, error) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("context '%s' not found in kubeconfig", name)
+}
+
+func (c *kubeconfig) marshal() (string, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// kubeconfigExecUser returns a user getting its token from the credential
+// plugin.
+func kubeconfigExecUser(e *execConfig) kubeconfigUser {
+	exec := map[string]interface{}{
+		"apiVersion":      e.apiVersion,
+		"command":         e.command,
+		"interactiveMode": "IfAvailable",
+	}
+	if len(e.args) > 0 {
+		exec["args"] = e.args
+	}
+	if len(e.env) > 0 {
+		var names []string
+		for name := range e.env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var env []map[string]string
+		for _, name := range names {
+			env = append(env, map[string]string{"name": name, "value": e.env[name]})
+		}
+		exec["env"] = env
+	}
+	return kubeconfigUser{Extra: map[string]interface{}{"exec": exec}}
+}
+
+// newKubeconfigExec returns a credential plugin for kubeconfig users to get
+// the token the provider uses. Tokens from token_path are printed by the
+// provider binary itself, an error is returned if the token is only known to
+// the provider.
+func newKubeconfigExec(d *schema.ResourceData, conn profile) (*execConfig, error) {
+	if v, ok := d.GetOk("exec"); ok {
+		ret := newExecConfig(v.([]interface{}))
+		return &ret, nil
+	}
+	if _, ok := d.GetOk("oidc"); ok {
+		return nil, fmt.Errorf("tokens from oidc are only known to the provider")
+	}
+	if conn.Token != "" || conn.TokenPath == "" {
+		return nil, fmt.Errorf("a static token is only known to the provider")
+	}
+	p, err := homedir.Expand(conn.TokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse token_path: %v", err)
+	}
+	// kubectl doesn't run in the working directory of terraform
+	if p, err = filepath.Abs(p); err != nil {
+		return nil, fmt.Errorf("can't parse token_path: %v", err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("can't find the provider binary: %v", err)
+	}
+	return &execConfig{
+		command:    self,
+		args:       []string{KubeconfigTokenFileCommand, p},
+		apiVersion: execDefaultAPIVersion,
+	}, nil
+}
+
+// RunKubeconfigTokenFile prints an ExecCredential with the token read from
+// the file given in args to w. Kubeconfigs of providers configured with
+// token_path run it as the credential plugin of their users.
+func RunKubeconfigTokenFile(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s TOKEN_FILE", KubeconfigTokenFileCommand)
+	}
+	raw, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("can't read token file: %v", err)
+	}
+	return json.NewEncoder(w).Encode(execCredential{
+		APIVersion: execDefaultAPIVersion,
+		Kind:       "ExecCredential",
+		Status:     &execCredentialStatus{Token: string(bytes.Trim(raw, "\n"))},
+	})
+}
+
+func decodeKubeconfigData(v string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func flattenKubeconfigAttributes(in *kubeconfigAttributes) []interface{} {
+	if in == nil {
+		return []interface{}{}
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"host":                   in.host,
+			"cluster_ca_certificate": in.clusterCACertificate,
+			"client_certificate":     in.clientCertificate,
+			"client_key":             in.clientKey,
+			"token":                  in.token,
+			"context_name":           in.contextName,
+			"cluster_name":           in.clusterName,
+			"user_name":              in.userName,
+		},
+	}
+}
@@ -0,0 +1,132 @@
+package metakube
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const testKubeconfig = `
+apiVersion: v1
+kind: Config
+clusters:
+- name: c1
+  cluster:
+    server: https://c1.metakube.example.com:6443
+    certificate-authority-data: Q0EgUEVN
+contexts:
+- name: default
+  context:
+    cluster: c1
+    user: admin
+current-context: default
+preferences: {}
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+func TestKubeconfigAttributes(t *testing.T) {
+	certs := strings.NewReplacer(
+		"token: admin-token", "client-certificate-data: "+base64.StdEncoding.EncodeToString([]byte("CERT PEM"))+"\n    client-key-data: "+base64.StdEncoding.EncodeToString([]byte("KEY PEM")),
+	).Replace(testKubeconfig)
+
+	testCases := []struct {
+		name    string
+		raw     string
+		want    *kubeconfigAttributes
+		wantErr string
+	}{
+		{
+			name: "token",
+			raw:  testKubeconfig,
+			want: &kubeconfigAttributes{
+				host:                 "https://c1.metakube.example.com:6443",
+				clusterCACertificate: "CA PEM",
+				token:                "admin-token",
+				contextName:          "default",
+				clusterName:          "c1",
+				userName:             "admin",
+			},
+		},
+		{
+			name: "client certificate",
+			raw:  certs,
+			want: &kubeconfigAttributes{
+				host:                 "https://c1.metakube.example.com:6443",
+				clusterCACertificate: "CA PEM",
+				clientCertificate:    "CERT PEM",
+				clientKey:            "KEY PEM",
+				contextName:          "default",
+				clusterName:          "c1",
+				userName:             "admin",
+			},
+		},
+		{
+			name:    "unknown current context",
+			raw:     strings.Replace(testKubeconfig, "current-context: default", "current-context: other", 1),
+			wantErr: "current context 'other' not found",
+		},
+		{
+			name:    "invalid",
+			raw:     "clusters: {",
+			wantErr: "can't parse kubeconfig",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := metakubeClusterKubeconfigAttributes(tc.raw)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(kubeconfigAttributes{})); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewKubeconfigExecTokenPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("secret-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	e, err := newKubeconfigExec(schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{}), profile{TokenPath: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	self, _ := os.Executable()
+	if e.command != self || len(e.args) != 2 || e.args[0] != KubeconfigTokenFileCommand {
+		t.Fatalf("want the provider binary as credential plugin, got %+v", e)
+	}
+
+	var out bytes.Buffer
+	if err := RunKubeconfigTokenFile(e.args[1:], &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got execCredential
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output is not an ExecCredential: %v: %s", err, out.String())
+	}
+	if got.Status == nil || got.Status.Token != "secret-token" || got.APIVersion != execDefaultAPIVersion {
+		t.Fatalf("unexpected ExecCredential %s", out.String())
+	}
+
+	if e, err := newKubeconfigExec(schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{}), profile{Token: "secret-token"}); err == nil {
+		t.Fatalf("want an error for a static token, got %+v", e)
+	}
+}
@@ -0,0 +1,153 @@
+package metakube
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// name of the terraform-plugin-log subsystem, TF_LOG_PROVIDER_METAKUBE sets its level
+const logSubsystem = "metakube"
+
+type loggerContextKey struct{}
+
+type crudFunc func(context.Context, *schema.ResourceData, interface{}) diag.Diagnostics
+
+// withLogContext adds the log context of an operation on a resource or data
+// source to the context passed to f.
+func withLogContext(resourceType, operation string, r *schema.Resource, f crudFunc) crudFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		k := m.(*metakubeProviderMeta)
+		fields := map[string]interface{}{
+			"resource_type": resourceType,
+			"operation":     operation,
+			"request_id":    newRequestID(),
+		}
+		if _, ok := r.Schema["project_id"]; ok {
+			fields["project_id"] = d.Get("project_id")
+		}
+		if _, ok := r.Schema["cluster_id"]; ok {
+			fields["cluster_id"] = d.Get("cluster_id")
+		} else if resourceType == "metakube_cluster" && d.Id() != "" {
+			fields["cluster_id"] = d.Id()
+		}
+		return f(k.logContext(ctx, fields), d, m)
+	}
+}
+
+// instrumentResource sets up logging and tracing for all operations of r.
+func instrumentResource(name string, r *schema.Resource) {
+	wrapResourceOperations(r, func(operation string, f crudFunc) crudFunc {
+		// the span is started first, so log messages can refer to it
+		return withTracing(name, operation, withLogContext(name, operation, r, f))
+	})
+}
+
+func wrapResourceOperations(r *schema.Resource, wrap func(operation string, f crudFunc) crudFunc) {
+	if r.CreateContext != nil {
+		r.CreateContext = schema.CreateContextFunc(wrap("create", crudFunc(r.CreateContext)))
+	}
+	if r.ReadContext != nil {
+		r.ReadContext = schema.ReadContextFunc(wrap("read", crudFunc(r.ReadContext)))
+	}
+	if r.UpdateContext != nil {
+		r.UpdateContext = schema.UpdateContextFunc(wrap("update", crudFunc(r.UpdateContext)))
+	}
+	if r.DeleteContext != nil {
+		r.DeleteContext = schema.DeleteContextFunc(wrap("delete", crudFunc(r.DeleteContext)))
+	}
+}
+
+// logContext returns a context carrying a logger with the given fields.
+// Messages are written to the provider's zap logger and to terraform-plugin-log.
+func (k *metakubeProviderMeta) logContext(ctx context.Context, fields map[string]interface{}) context.Context {
+	ctx = tflog.NewSubsystem(ctx, logSubsystem)
+	zapFields := make([]zap.Field, 0, len(fields))
+	for key, v := range fields {
+		ctx = tflog.SubsystemSetField(ctx, logSubsystem, key, v)
+		zapFields = append(zapFields, zap.Any(key, v))
+	}
+	core := zapcore.NewTee(
+		k.log.Desugar().Core().With(zapFields),
+		&tflogCore{ctx: ctx, debug: k.debug},
+	)
+	return context.WithValue(ctx, loggerContextKey{}, zap.New(core).Sugar())
+}
+
+// logger returns the logger of the operation ctx belongs to.
+func (k *metakubeProviderMeta) logger(ctx context.Context) *zap.SugaredLogger {
+	return loggerFromContext(ctx, k.log)
+}
+
+func loggerFromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(loggerContextKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return fallback
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// tflogCore is a zapcore.Core writing to terraform-plugin-log, so messages
+// show up in Terraform's log next to its own messages for the same resource.
+// Debug messages are logged at trace level unless debug or development mode
+// is enabled.
+type tflogCore struct {
+	ctx    context.Context
+	debug  bool
+	fields []zapcore.Field
+}
+
+func (c *tflogCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *tflogCore) With(fields []zapcore.Field) zapcore.Core {
+	return &tflogCore{
+		ctx:    c.ctx,
+		debug:  c.debug,
+		fields: append(append([]zapcore.Field{}, c.fields...), fields...),
+	}
+}
+
+func (c *tflogCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(e, c)
+}
+
+func (c *tflogCore) Write(e zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	switch {
+	case e.Level == zapcore.DebugLevel && !c.debug:
+		tflog.SubsystemTrace(c.ctx, logSubsystem, e.Message, enc.Fields)
+	case e.Level == zapcore.DebugLevel:
+		tflog.SubsystemDebug(c.ctx, logSubsystem, e.Message, enc.Fields)
+	case e.Level == zapcore.InfoLevel:
+		tflog.SubsystemInfo(c.ctx, logSubsystem, e.Message, enc.Fields)
+	case e.Level == zapcore.WarnLevel:
+		tflog.SubsystemWarn(c.ctx, logSubsystem, e.Message, enc.Fields)
+	default:
+		tflog.SubsystemError(c.ctx, logSubsystem, e.Message, enc.Fields)
+	}
+	return nil
+}
+
+func (c *tflogCore) Sync() error {
+	return nil
+}
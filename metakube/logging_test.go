@@ -0,0 +1,117 @@
+package metakube
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"go.uber.org/zap"
+)
+
+func TestLoggerWritesToTFLog(t *testing.T) {
+	testCases := []struct {
+		name      string
+		debug     bool
+		log       func(*zap.SugaredLogger)
+		wantLevel string
+	}{
+		{
+			name:      "debug as trace",
+			log:       func(l *zap.SugaredLogger) { l.Debugf("waiting for %s", "cluster") },
+			wantLevel: "trace",
+		},
+		{
+			name:      "debug mode",
+			debug:     true,
+			log:       func(l *zap.SugaredLogger) { l.Debugf("waiting for %s", "cluster") },
+			wantLevel: "debug",
+		},
+		{
+			name:      "warn",
+			log:       func(l *zap.SugaredLogger) { l.Warnf("waiting for %s", "cluster") },
+			wantLevel: "warn",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			ctx := tflogtest.RootLogger(context.Background(), &out)
+			k := &metakubeProviderMeta{log: zap.NewNop().Sugar(), debug: tc.debug}
+			ctx = k.logContext(ctx, map[string]interface{}{"project_id": "p1"})
+
+			tc.log(k.logger(ctx))
+
+			entries, err := tflogtest.MultilineJSONDecode(&out)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("want 1 entry, got %v", entries)
+			}
+			want := map[string]interface{}{
+				"@level":     tc.wantLevel,
+				"@message":   "waiting for cluster",
+				"@module":    "provider." + logSubsystem,
+				"project_id": "p1",
+			}
+			if diff := cmp.Diff(want, entries[0]); diff != "" {
+				t.Fatalf("unexpected entry, diff: %s", diff)
+			}
+		})
+	}
+}
+
+func TestInstrumentResourceLogContext(t *testing.T) {
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"project_id": {Type: schema.TypeString, Optional: true},
+			"cluster_id": {Type: schema.TypeString, Optional: true},
+		},
+		ReadContext: func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+			m.(*metakubeProviderMeta).logger(ctx).With("name", "pool").Info("reading")
+			return nil
+		},
+	}
+	instrumentResource("metakube_node_deployment", r)
+
+	var out bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &out)
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{
+		"project_id": "p1",
+		"cluster_id": "c1",
+	})
+	k := &metakubeProviderMeta{log: zap.NewNop().Sugar()}
+	if diags := r.ReadContext(ctx, d, k); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("want 1 entry, got %v", entries)
+	}
+	fields := entries[0]
+	if fields["request_id"] == "" || fields["request_id"] == nil {
+		t.Error("want request_id to be set")
+	}
+	delete(fields, "request_id")
+	want := map[string]interface{}{
+		"@level":        "info",
+		"@message":      "reading",
+		"@module":       "provider." + logSubsystem,
+		"project_id":    "p1",
+		"cluster_id":    "c1",
+		"resource_type": "metakube_node_deployment",
+		"operation":     "read",
+		"name":          "pool",
+	}
+	if diff := cmp.Diff(want, fields); diff != "" {
+		t.Fatalf("unexpected entry, diff: %s", diff)
+	}
+}
@@ -0,0 +1,166 @@
+package metakube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/syseleven/go-metakube/client/project"
+	"github.com/syseleven/go-metakube/models"
+)
+
+const (
+	// number of most recent events listed in not ready diagnostics
+	notReadyEventsLimit = 10
+	// time given to list events once waiting has timed out
+	notReadyEventsTimeout = 30 * time.Second
+)
+
+// notReadyError is returned when a cluster or node deployment did not become
+// ready in time. It keeps what was last observed, to tell what is not ready.
+type notReadyError struct {
+	err error
+
+	// cluster health and the components waited for
+	health     *models.ClusterHealth
+	components []string
+
+	// node deployment replicas and nodes
+	replicas *int32
+	status   *models.MachineDeploymentStatus
+	nodes    []*models.Node
+
+	events []*models.Event
+}
+
+func (e *notReadyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *notReadyError) Unwrap() error {
+	return e.err
+}
+
+// downComponents returns the components waited for that are not up, with
+// their status.
+func (e *notReadyError) downComponents() []string {
+	if e.health == nil {
+		return nil
+	}
+	components := e.components
+	if len(components) == 0 {
+		components = clusterHealthComponentNames
+	}
+	statuses := metakubeClusterHealthComponents(e.health)
+	var ret []string
+	for _, c := range components {
+		if s := statuses[c]; s != clusterHealthUp {
+			ret = append(ret, fmt.Sprintf("%s (%s)", c, flattenClusterHealthStatus(s)))
+		}
+	}
+	return ret
+}
+
+// notReadyNodes returns the names of the nodes that did not report node info.
+func (e *notReadyError) notReadyNodes() []string {
+	var ret []string
+	for _, n := range e.nodes {
+		if n.Status == nil || n.Status.NodeInfo == nil || n.Status.NodeInfo.KernelVersion == "" {
+			ret = append(ret, n.Name)
+		}
+	}
+	return ret
+}
+
+func (e *notReadyError) detail() string {
+	var lines []string
+	if down := e.downComponents(); len(down) > 0 {
+		lines = append(lines, fmt.Sprintf("Components not up: %s", strings.Join(down, ", ")))
+	}
+	if e.replicas != nil {
+		var ready, unavailable int32
+		if e.status != nil {
+			ready, unavailable = e.status.ReadyReplicas, e.status.UnavailableReplicas
+		}
+		lines = append(lines, fmt.Sprintf("Replicas: %d desired, %d ready, %d unavailable, %d nodes", *e.replicas, ready, unavailable, len(e.nodes)))
+	}
+	if nodes := e.notReadyNodes(); len(nodes) > 0 {
+		lines = append(lines, fmt.Sprintf("Nodes without node info: %s", strings.Join(nodes, ", ")))
+	}
+	if len(e.events) > 0 {
+		lines = append(lines, "Recent events:")
+		for _, ev := range e.events {
+			var object string
+			if o := ev.InvolvedObject; o != nil {
+				object = fmt.Sprintf(" %s/%s", o.Type, o.Name)
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s%s: %s", ev.LastTimestamp, ev.Type, object, ev.Message))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// notReadyDiagnostics returns a diagnostic for err, detailing what was not
+// ready if err is a notReadyError.
+func notReadyDiagnostics(err error, severity diag.Severity, summary string) diag.Diagnostics {
+	d := diag.Diagnostic{
+		Severity: severity,
+		Summary:  fmt.Sprintf("%s: %v", summary, err),
+	}
+	var e *notReadyError
+	if errors.As(err, &e) {
+		d.Detail = e.detail()
+	}
+	return diag.Diagnostics{d}
+}
+
+// recentEvents returns the most recent events, newest last.
+func recentEvents(events []*models.Event) []*models.Event {
+	sort.SliceStable(events, func(i, j int) bool {
+		return time.Time(events[i].LastTimestamp).Before(time.Time(events[j].LastTimestamp))
+	})
+	if len(events) > notReadyEventsLimit {
+		events = events[len(events)-notReadyEventsLimit:]
+	}
+	return events
+}
+
+// detachedContext keeps the values of its parent, like the logger and trace
+// span, but not its deadline, which has passed when waiting timed out.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func metakubeClusterEvents(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) []*models.Event {
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, notReadyEventsTimeout)
+	defer cancel()
+	p := project.NewGetClusterEventsV2Params().WithContext(ctx).WithProjectID(projectID).WithClusterID(clusterID)
+	r, err := k.client.Project.GetClusterEventsV2(p, k.auth)
+	if err != nil {
+		k.logger(ctx).Debugf("unable to list cluster '%s' events: %v", clusterID, newAPIError(err))
+		return nil
+	}
+	return recentEvents(r.Payload)
+}
+
+func metakubeNodeDeploymentEvents(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID, id string) []*models.Event {
+	ctx, cancel := context.WithTimeout(detachedContext{ctx}, notReadyEventsTimeout)
+	defer cancel()
+	p := project.NewListMachineDeploymentNodesEventsParams().WithContext(ctx).WithProjectID(projectID).WithClusterID(clusterID).WithMachineDeploymentID(id)
+	r, err := k.client.Project.ListMachineDeploymentNodesEvents(p, k.auth)
+	if err != nil {
+		k.logger(ctx).Debugf("unable to list node deployment '%s' events: %v", id, newAPIError(err))
+		return nil
+	}
+	return recentEvents(r.Payload)
+}
@@ -0,0 +1,77 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/syseleven/go-metakube/models"
+)
+
+func TestNotReadyErrorDetail(t *testing.T) {
+	replicas := int32(2)
+	e := &notReadyError{
+		err: errors.New("timeout"),
+		health: &models.ClusterHealth{
+			Apiserver:         clusterHealthUp,
+			MachineController: clusterHealthProvisioning,
+		},
+		components: []string{"apiserver", "machine_controller"},
+		replicas:   &replicas,
+		status:     &models.MachineDeploymentStatus{ReadyReplicas: 1, UnavailableReplicas: 1},
+		nodes: []*models.Node{
+			{Name: "node-a", Status: &models.NodeStatus{NodeInfo: &models.NodeSystemInfo{KernelVersion: "6.1"}}},
+			{Name: "node-b", Status: &models.NodeStatus{}},
+		},
+		events: []*models.Event{{
+			Type:           "Warning",
+			Message:        "quota exceeded",
+			LastTimestamp:  strfmt.DateTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+			InvolvedObject: &models.ObjectReferenceResource{Type: "machine", Name: "node-b"},
+		}},
+	}
+	want := strings.Join([]string{
+		"Components not up: machine_controller (provisioning)",
+		"Replicas: 2 desired, 1 ready, 1 unavailable, 2 nodes",
+		"Nodes without node info: node-b",
+		"Recent events:",
+		"  2024-01-02T03:04:05.000Z Warning machine/node-b: quota exceeded",
+	}, "\n")
+	if diff := cmp.Diff(want, e.detail()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMetakubeResourceClusterWaitForReadyTimeout(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/projects/p1/clusters/c1/health":
+			_ = json.NewEncoder(w).Encode(map[string]int{"apiserver": 1, "etcd": 1, "machineController": 0})
+		case "/api/v2/projects/p1/clusters/c1/events":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"type": "Warning", "message": "etcd backup failed"}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := metakubeResourceClusterWaitForReady(ctx, k, time.Second, "p1", "c1", []string{"apiserver", "machine_controller"})
+	got := notReadyDiagnostics(err, diag.Error, "cluster is not ready")
+	if len(got) != 1 || got[0].Severity != diag.Error {
+		t.Fatalf("want one error, got %v", got)
+	}
+	for _, s := range []string{"Components not up: machine_controller (down)", "etcd backup failed"} {
+		if !strings.Contains(got[0].Detail, s) {
+			t.Fatalf("want detail containing %q, got %q", s, got[0].Detail)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package metakube
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultHost       = "https://metakube.syseleven.de"
+	defaultTokenPath  = "~/.metakube/auth"
+	defaultConfigPath = "~/.metakube/config"
+)
+
+// profileConfig is the content of the MetaKube config file. Like a
+// kubeconfig it holds named contexts and the one used by default.
+type profileConfig struct {
+	CurrentContext string         `yaml:"current-context"`
+	Contexts       []profileEntry `yaml:"contexts"`
+}
+
+type profileEntry struct {
+	Name    string  `yaml:"name"`
+	Context profile `yaml:"context"`
+}
+
+type profile struct {
+	Host        string `yaml:"host"`
+	Token       string `yaml:"token"`
+	TokenPath   string `yaml:"token_path"`
+	ProjectID   string `yaml:"project_id"`
+	ProjectName string `yaml:"project_name"`
+}
+
+// connectionSettings returns the host, token and default project settings of
+// the provider. Values set in the configuration or in the environment take
+// precedence over those of the selected profile.
+func connectionSettings(d *schema.ResourceData) (profile, diag.Diagnostics) {
+	ret, diagnostics := loadProfile(d.Get("config_path").(string), d.Get("profile").(string))
+	if diagnostics.HasError() {
+		return ret, diagnostics
+	}
+
+	if v := d.Get("host").(string); v != "" {
+		ret.Host = v
+	}
+	if ret.Host == "" {
+		ret.Host = defaultHost
+	}
+
+	token, tokenPath := d.Get("token").(string), d.Get("token_path").(string)
+	if token != "" || tokenPath != "" {
+		ret.Token, ret.TokenPath = token, tokenPath
+	}
+	if ret.Token == "" && ret.TokenPath == "" {
+		ret.TokenPath = defaultTokenPath
+	}
+
+	projectID, projectName := d.Get("project_id").(string), d.Get("project_name").(string)
+	if projectID != "" || projectName != "" {
+		ret.ProjectID, ret.ProjectName = projectID, projectName
+	}
+
+	return ret, nil
+}
+
+// loadProfile returns the named profile from the config file at path, or
+// its current context if name is empty. A missing config file is only an
+// error if a profile was asked for.
+func loadProfile(path, name string) (profile, diag.Diagnostics) {
+	configPath := path
+	if configPath == "" {
+		configPath = defaultConfigPath
+	}
+	raw, err := readPath(configPath)
+	if errors.Is(err, fs.ErrNotExist) && name == "" && path == "" {
+		return profile{}, nil
+	}
+	if err != nil {
+		return profile{}, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       fmt.Sprintf("Can't read config file: %v", err),
+			AttributePath: cty.GetAttrPath("config_path"),
+		}}
+	}
+
+	var config profileConfig
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return profile{}, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       fmt.Sprintf("Can't parse config file '%s': %v", configPath, err),
+			AttributePath: cty.GetAttrPath("config_path"),
+		}}
+	}
+
+	if name == "" {
+		name = config.CurrentContext
+	}
+	if name == "" {
+		return profile{}, nil
+	}
+
+	var names []string
+	for _, c := range config.Contexts {
+		if c.Name == name {
+			return c.Context, nil
+		}
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return profile{}, diag.Diagnostics{{
+		Severity:      diag.Error,
+		Summary:       fmt.Sprintf("Profile '%s' not found in '%s'", name, configPath),
+		Detail:        fmt.Sprintf("Available profiles: %s", strings.Join(names, ", ")),
+		AttributePath: cty.GetAttrPath("profile"),
+	}}
+}
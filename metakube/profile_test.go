@@ -0,0 +1,119 @@
+package metakube
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const testProfileConfig = `
+current-context: prod
+contexts:
+- name: prod
+  context:
+    host: https://metakube.example.com
+    token_path: ~/.metakube/prod
+    project_id: prod-project
+- name: onprem
+  context:
+    host: https://metakube.internal
+    token: onprem-token
+    project_name: platform
+`
+
+func TestConnectionSettings(t *testing.T) {
+	for _, env := range []string{"METAKUBE_HOST", "METAKUBE_TOKEN", "METAKUBE_TOKEN_PATH", "METAKUBE_PROFILE", "METAKUBE_CONFIG", "METAKUBE_PROJECT_ID", "METAKUBE_PROJECT_NAME"} {
+		t.Setenv(env, "")
+	}
+	configPath := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(configPath, []byte(testProfileConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		raw     map[string]interface{}
+		env     map[string]string
+		want    profile
+		wantErr string
+	}{
+		{
+			name:    "no config file",
+			raw:     map[string]interface{}{"config_path": filepath.Join(t.TempDir(), "missing")},
+			wantErr: "Can't read config file",
+		},
+		{
+			name: "current context",
+			raw:  map[string]interface{}{"config_path": configPath},
+			want: profile{Host: "https://metakube.example.com", TokenPath: "~/.metakube/prod", ProjectID: "prod-project"},
+		},
+		{
+			name: "profile",
+			raw:  map[string]interface{}{"config_path": configPath, "profile": "onprem"},
+			want: profile{Host: "https://metakube.internal", Token: "onprem-token", ProjectName: "platform"},
+		},
+		{
+			name: "profile from environment",
+			raw:  map[string]interface{}{"config_path": configPath},
+			env:  map[string]string{"METAKUBE_PROFILE": "onprem"},
+			want: profile{Host: "https://metakube.internal", Token: "onprem-token", ProjectName: "platform"},
+		},
+		{
+			name: "explicit attributes take precedence",
+			raw: map[string]interface{}{
+				"config_path": configPath,
+				"profile":     "onprem",
+				"token_path":  "~/token",
+				"project_id":  "other-project",
+			},
+			env:  map[string]string{"METAKUBE_HOST": "https://metakube.test"},
+			want: profile{Host: "https://metakube.test", TokenPath: "~/token", ProjectID: "other-project"},
+		},
+		{
+			name:    "unknown profile",
+			raw:     map[string]interface{}{"config_path": configPath, "profile": "dev"},
+			wantErr: "Profile 'dev' not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			d := schema.TestResourceDataRaw(t, Provider().Schema, tc.raw)
+			got, diagnostics := connectionSettings(d)
+			if tc.wantErr != "" {
+				if !diagnostics.HasError() || !strings.Contains(diagnostics[0].Summary, tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, diagnostics)
+				}
+				return
+			}
+			if diagnostics.HasError() {
+				t.Fatalf("unexpected error: %v", diagnostics)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectionSettingsDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range []string{"METAKUBE_HOST", "METAKUBE_TOKEN", "METAKUBE_TOKEN_PATH", "METAKUBE_PROFILE", "METAKUBE_CONFIG"} {
+		t.Setenv(env, "")
+	}
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{})
+	got, diagnostics := connectionSettings(d)
+	if diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", diagnostics)
+	}
+	want := profile{Host: defaultHost, TokenPath: defaultTokenPath}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+}
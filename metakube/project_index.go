@@ -0,0 +1,141 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/syseleven/go-metakube/client/project"
+)
+
+// number of projects searched at the same time when looking up the owner project
+const projectIndexConcurrency = 8
+
+// projectIndex finds the project owning a cluster or an SSH key, for resources
+// imported without project_id. Projects are searched concurrently and every
+// object seen along the way is remembered, so later lookups of clusters and
+// keys in the same projects don't send any requests.
+type projectIndex struct {
+	concurrency int
+
+	mu       sync.Mutex
+	clusters map[string]string
+	sshKeys  map[string]string
+}
+
+func newProjectIndex() *projectIndex {
+	return &projectIndex{
+		concurrency: projectIndexConcurrency,
+		clusters:    make(map[string]string),
+		sshKeys:     make(map[string]string),
+	}
+}
+
+// projectObjectLister returns the ids of all objects of one kind in a project.
+type projectObjectLister func(ctx context.Context, projectID string) ([]string, error)
+
+func (i *projectIndex) findCluster(ctx context.Context, id string, meta *metakubeProviderMeta) (string, error) {
+	return i.find(ctx, i.clusters, id, meta, func(ctx context.Context, projectID string) ([]string, error) {
+		p := project.NewListClustersV2Params().WithContext(ctx).WithProjectID(projectID)
+		r, err := meta.client.Project.ListClustersV2(p, meta.auth)
+		if err != nil {
+			meta.logger(ctx).Debugf("lookup owner project: list clusters: %v", err)
+			return nil, fmt.Errorf("list clusters: %w", newAPIError(err))
+		}
+		ids := make([]string, 0, len(r.Payload))
+		for _, item := range r.Payload {
+			ids = append(ids, item.ID)
+		}
+		return ids, nil
+	})
+}
+
+func (i *projectIndex) findSSHKey(ctx context.Context, id string, meta *metakubeProviderMeta) (string, error) {
+	return i.find(ctx, i.sshKeys, id, meta, func(ctx context.Context, projectID string) ([]string, error) {
+		p := project.NewListSSHKeysParams().WithContext(ctx).WithProjectID(projectID)
+		r, err := meta.client.Project.ListSSHKeys(p, meta.auth)
+		if err != nil {
+			return nil, fmt.Errorf("list sshkeys: %w", newAPIError(err))
+		}
+		ids := make([]string, 0, len(r.Payload))
+		for _, item := range r.Payload {
+			ids = append(ids, item.ID)
+		}
+		return ids, nil
+	})
+}
+
+// find returns the id of the project owning the object or an empty string if
+// no project does. The search stops as soon as the owner is found; an error
+// is returned only if the object wasn't found in any of the other projects.
+func (i *projectIndex) find(ctx context.Context, index map[string]string, id string, meta *metakubeProviderMeta, list projectObjectLister) (string, error) {
+	i.mu.Lock()
+	owner, ok := index[id]
+	i.mu.Unlock()
+	if ok {
+		return owner, nil
+	}
+
+	r, err := meta.client.Project.ListProjects(project.NewListProjectsParams().WithContext(ctx), meta.auth)
+	if err != nil {
+		return "", fmt.Errorf("list projects: %w", newAPIError(err))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, i.concurrency)
+		errMu    sync.Mutex
+		firstErr error
+	)
+	for _, prj := range r.Payload {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(projectID string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			ids, err := list(ctx, projectID)
+			if err != nil {
+				if ctx.Err() == nil {
+					errMu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					errMu.Unlock()
+				}
+				return
+			}
+			i.mu.Lock()
+			for _, v := range ids {
+				index[v] = projectID
+			}
+			_, found := index[id]
+			i.mu.Unlock()
+			if found {
+				cancel()
+			}
+		}(prj.ID)
+	}
+	wg.Wait()
+
+	i.mu.Lock()
+	owner, ok = index[id]
+	i.mu.Unlock()
+	if ok {
+		return owner, nil
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return "", nil
+}
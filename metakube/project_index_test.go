@@ -0,0 +1,178 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testProjectServer struct {
+	handler http.Handler
+
+	mu       sync.Mutex
+	requests map[string]int
+	inFlight int32
+	maxSeen  int32
+}
+
+// newTestProjectServer serves n projects, project pN owns cluster cN and key kN.
+// Listing the clusters of project p-fail returns an error.
+func newTestProjectServer(t *testing.T, n int, failing bool) *testProjectServer {
+	t.Helper()
+	s := &testProjectServer{requests: make(map[string]int)}
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := atomic.AddInt32(&s.inFlight, 1)
+		defer atomic.AddInt32(&s.inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&s.maxSeen)
+			if cur <= max || atomic.CompareAndSwapInt32(&s.maxSeen, max, cur) {
+				break
+			}
+		}
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case r.URL.Path == "/api/v1/projects":
+			var projects []map[string]string
+			for i := 0; i < n; i++ {
+				projects = append(projects, map[string]string{"id": fmt.Sprintf("p%d", i)})
+			}
+			if failing {
+				projects = append(projects, map[string]string{"id": "p-fail"})
+			}
+			_ = json.NewEncoder(w).Encode(projects)
+		case len(parts) == 5 && parts[3] == "p-fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": 500, "message": "boom"}})
+		case len(parts) == 5 && parts[4] == "clusters":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"id": "c" + strings.TrimPrefix(parts[3], "p")}})
+		case len(parts) == 5 && parts[4] == "sshkeys":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"id": "k" + strings.TrimPrefix(parts[3], "p")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	return s
+}
+
+func (s *testProjectServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func newTestProjectIndexMeta(t *testing.T, s *testProjectServer) *metakubeProviderMeta {
+	t.Helper()
+	k := newTestProviderMeta(t, s.handler)
+	k.projects = newProjectIndex()
+	return k
+}
+
+func TestProjectIndexFindCluster(t *testing.T) {
+	s := newTestProjectServer(t, 50, false)
+	k := newTestProjectIndexMeta(t, s)
+
+	got, err := k.projects.findCluster(context.Background(), "c42", k)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "p42" {
+		t.Fatalf("want p42, got %s", got)
+	}
+	if max := atomic.LoadInt32(&s.maxSeen); max > projectIndexConcurrency {
+		t.Fatalf("want at most %d concurrent requests, got %d", projectIndexConcurrency, max)
+	}
+
+	// the owner of a cluster seen before is remembered
+	listed := s.count("/api/v1/projects")
+	got, err = k.projects.findCluster(context.Background(), "c42", k)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "p42" {
+		t.Fatalf("want p42, got %s", got)
+	}
+	if s.count("/api/v1/projects") != listed {
+		t.Fatal("expected memoised owner project to be used")
+	}
+}
+
+func TestProjectIndexFindSSHKey(t *testing.T) {
+	s := newTestProjectServer(t, 5, false)
+	k := newTestProjectIndexMeta(t, s)
+
+	got, err := k.projects.findSSHKey(context.Background(), "k3", k)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "p3" {
+		t.Fatalf("want p3, got %s", got)
+	}
+	// keys and clusters are indexed separately
+	got, err = k.projects.findCluster(context.Background(), "k3", k)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "" {
+		t.Fatalf("want no owner, got %s", got)
+	}
+}
+
+func TestProjectIndexNotFound(t *testing.T) {
+	s := newTestProjectServer(t, 5, false)
+	k := newTestProjectIndexMeta(t, s)
+
+	got, err := k.projects.findCluster(context.Background(), "missing", k)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "" {
+		t.Fatalf("want no owner, got %s", got)
+	}
+	for i := 0; i < 5; i++ {
+		if n := s.count(fmt.Sprintf("/api/v2/projects/p%d/clusters", i)); n != 1 {
+			t.Fatalf("want project p%d to be searched once, got %d", i, n)
+		}
+	}
+}
+
+func TestProjectIndexError(t *testing.T) {
+	s := newTestProjectServer(t, 3, true)
+	k := newTestProjectIndexMeta(t, s)
+
+	if _, err := k.projects.findCluster(context.Background(), "missing", k); err == nil {
+		t.Fatal("expected error when a project can't be searched")
+	}
+
+	// an error in another project doesn't matter once the owner is found
+	got, err := k.projects.findCluster(context.Background(), "c1", k)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "p1" {
+		t.Fatalf("want p1, got %s", got)
+	}
+}
+
+func TestProjectIndexListProjectsError(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	k.projects = newProjectIndex()
+
+	_, err := k.projects.findCluster(context.Background(), "c1", k)
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("want API error, got %v", err)
+	}
+}
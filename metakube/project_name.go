@@ -0,0 +1,152 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/syseleven/go-metakube/client/project"
+)
+
+func metakubeResourceProjectIDSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		Computed:      true,
+		ForceNew:      true,
+		ValidateFunc:  validation.NoZeroValues,
+		ConflictsWith: []string{"project_name"},
+		Description:   "The id of the project resource belongs to, defaults to the provider project",
+	}
+}
+
+func metakubeResourceProjectNameSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeString,
+		Optional:      true,
+		ValidateFunc:  validation.NoZeroValues,
+		ConflictsWith: []string{"project_id"},
+		Description:   "The name of the project resource belongs to, used to look up project_id",
+	}
+}
+
+// metakubeResourceWithProjectName adds project_id and project_name to r, a
+// resource whose other arguments all force a new resource. project_name can
+// change in place as long as it resolves to the same project, so r gets an
+// update that has nothing to do.
+func metakubeResourceWithProjectName(r *schema.Resource) *schema.Resource {
+	r.Schema["project_id"] = metakubeResourceProjectIDSchema()
+	r.Schema["project_name"] = metakubeResourceProjectNameSchema()
+	r.UpdateContext = schema.NoopContext
+	r.CustomizeDiff = metakubeResourceProjectDiff
+	return r
+}
+
+// metakubeProjectIDByName returns the id of the project with the given name.
+func metakubeProjectIDByName(ctx context.Context, k *metakubeProviderMeta, name string) (string, error) {
+	p := project.NewListProjectsParams().WithContext(ctx)
+	res, err := k.client.Project.ListProjects(p, k.auth)
+	if err != nil {
+		return "", fmt.Errorf("list projects: %w", newAPIError(err))
+	}
+
+	var ids []string
+	for _, r := range res.Payload {
+		if r != nil && r.Name == name {
+			ids = append(ids, r.ID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("could not find a project with name '%s'", name)
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("found %d projects with name '%s' (%s), use the project id instead", len(ids), name, strings.Join(ids, ", "))
+	}
+}
+
+// projectID returns the id of the project a new resource belongs to. The
+// project name set on the resource takes precedence over the provider defaults.
+func (k *metakubeProviderMeta) projectID(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		if k.defaultProjectID != "" {
+			return k.defaultProjectID, nil
+		}
+		name = k.defaultProjectName
+	}
+	if name == "" {
+		return "", fmt.Errorf("project_id or project_name must be set on the resource or the provider")
+	}
+	return metakubeProjectIDByName(ctx, k, name)
+}
+
+// metakubeResourceProjectID returns the project id of the resource. If it
+// is not known yet it is resolved and stored in the state.
+func metakubeResourceProjectID(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) (string, diag.Diagnostics) {
+	if id := d.Get("project_id").(string); id != "" {
+		return id, nil
+	}
+	id, err := k.projectID(ctx, d.Get("project_name").(string))
+	if err != nil {
+		return "", diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       err.Error(),
+			AttributePath: cty.GetAttrPath("project_name"),
+		}}
+	}
+	_ = d.Set("project_id", id)
+	return id, nil
+}
+
+// metakubeResourceProjectDiff plans the project id of resources configured
+// by project name, so that it is shown in the plan. Existing resources are
+// only replaced if the name resolves to another project. Project names that
+// are not known until apply are resolved by metakubeResourceProjectID.
+func metakubeResourceProjectDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	k, ok := m.(*metakubeProviderMeta)
+	if !ok || (d.Id() != "" && !d.HasChange("project_name")) {
+		return nil
+	}
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	if id := config.GetAttr("project_id"); !id.IsKnown() || !id.IsNull() {
+		return nil
+	}
+	name := config.GetAttr("project_name")
+	if !name.IsKnown() {
+		if d.Id() == "" {
+			return nil
+		}
+		// the name may resolve to another project
+		if err := d.SetNewComputed("project_id"); err != nil {
+			return err
+		}
+		return d.ForceNew("project_id")
+	}
+
+	var v string
+	if !name.IsNull() {
+		v = name.AsString()
+	}
+	id, err := k.projectID(ctx, v)
+	if err != nil {
+		return err
+	}
+	if d.Id() == "" {
+		return d.SetNew("project_id", id)
+	}
+	if old, _ := d.GetChange("project_id"); old.(string) == id {
+		return nil
+	}
+	if err := d.SetNew("project_id", id); err != nil {
+		return err
+	}
+	return d.ForceNew("project_id")
+}
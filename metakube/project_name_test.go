@@ -0,0 +1,171 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func newTestProjectNameMeta(t *testing.T) *metakubeProviderMeta {
+	t.Helper()
+	return newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/projects" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]map[string]string{
+			{"id": "p1", "name": "staging"},
+			{"id": "p2", "name": "production"},
+			{"id": "p3", "name": "shared"},
+			{"id": "p4", "name": "shared"},
+		})
+	}))
+}
+
+func TestProviderMetaProjectID(t *testing.T) {
+	testCases := []struct {
+		name               string
+		projectName        string
+		defaultProjectID   string
+		defaultProjectName string
+		want               string
+		wantErr            string
+	}{
+		{
+			name:        "project name",
+			projectName: "production",
+			want:        "p2",
+		},
+		{
+			name:             "project name over provider default",
+			projectName:      "production",
+			defaultProjectID: "p1",
+			want:             "p2",
+		},
+		{
+			name:             "provider project id",
+			defaultProjectID: "p1",
+			want:             "p1",
+		},
+		{
+			name:               "provider project name",
+			defaultProjectName: "staging",
+			want:               "p1",
+		},
+		{
+			name:        "ambiguous name",
+			projectName: "shared",
+			wantErr:     "found 2 projects with name 'shared' (p3, p4)",
+		},
+		{
+			name:        "unknown name",
+			projectName: "missing",
+			wantErr:     "could not find a project with name 'missing'",
+		},
+		{
+			name:    "no project",
+			wantErr: "project_id or project_name must be set",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k := newTestProjectNameMeta(t)
+			k.defaultProjectID = tc.defaultProjectID
+			k.defaultProjectName = tc.defaultProjectName
+
+			got, err := k.projectID(context.Background(), tc.projectName)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMetakubeResourceProjectID(t *testing.T) {
+	k := newTestProjectNameMeta(t)
+	d := schema.TestResourceDataRaw(t, metakubeResourceSSHKey().Schema, map[string]interface{}{
+		"project_name": "staging",
+		"name":         "key",
+		"public_key":   "ssh-rsa AAAA",
+	})
+
+	got, diagnostics := metakubeResourceProjectID(context.Background(), d, k)
+	if diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", diagnostics)
+	}
+	if got != "p1" {
+		t.Fatalf("want p1, got %s", got)
+	}
+	if v := d.Get("project_id"); v != "p1" {
+		t.Fatalf("project_id was not stored, got %v", v)
+	}
+}
+
+func TestMetakubeResourceProjectDiffExisting(t *testing.T) {
+	k := newTestProjectNameMeta(t)
+	state := &terraform.InstanceState{ID: "k1", Attributes: map[string]string{
+		"project_id": "p1",
+		"name":       "key",
+		"public_key": "ssh-rsa AAAA",
+	}}
+
+	testCases := []struct {
+		projectName string
+		wantNew     bool
+	}{
+		{projectName: "staging"},
+		{projectName: "production", wantNew: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.projectName, func(t *testing.T) {
+			r := metakubeResourceSSHKey()
+			config := map[string]interface{}{
+				"project_name": tc.projectName,
+				"name":         "key",
+				"public_key":   "ssh-rsa AAAA",
+			}
+			state := state.DeepCopy()
+			state.RawConfig = testRawConfig(r, config)
+			d, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), k)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := d.RequiresNew(); got != tc.wantNew {
+				t.Fatalf("want replacement %v, got %v", tc.wantNew, got)
+			}
+			if tc.wantNew && d.Attributes["project_id"].New != "p2" {
+				t.Fatalf("want project_id p2, got %v", d.Attributes["project_id"])
+			}
+		})
+	}
+}
+
+// testRawConfig returns the configuration as Terraform sends it, the diff
+// functions of tests only get the legacy configuration.
+func testRawConfig(r *schema.Resource, config map[string]interface{}) cty.Value {
+	ty := r.CoreConfigSchema().ImpliedType()
+	vals := make(map[string]cty.Value)
+	for name, t := range ty.AttributeTypes() {
+		vals[name] = cty.NullVal(t)
+	}
+	for name, v := range config {
+		vals[name] = cty.StringVal(v.(string))
+	}
+	return cty.ObjectVal(vals)
+}
@@ -5,17 +5,21 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"time"
 
 	"github.com/go-openapi/runtime"
+	httptransport "github.com/go-openapi/runtime/client"
 	"github.com/go-openapi/strfmt"
 	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/mitchellh/go-homedir"
 	k8client "github.com/syseleven/go-metakube/client"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -26,9 +30,26 @@ const (
 )
 
 type metakubeProviderMeta struct {
-	client *k8client.MetaKubeAPI
-	auth   runtime.ClientAuthInfoWriter
-	log    *zap.SugaredLogger
+	client   *k8client.MetaKubeAPI
+	auth     runtime.ClientAuthInfoWriter
+	log      *zap.SugaredLogger
+	limiter  *apiLimiter
+	cache    *apiCache
+	projects *projectIndex
+	// log debug messages to terraform-plugin-log at debug instead of trace level
+	debug bool
+	// nil if tracing is disabled
+	tracerProvider *sdktrace.TracerProvider
+	// labels added to all clusters and node deployments
+	defaultLabels map[string]string
+	// project of resources that set neither project_id nor project_name
+	defaultProjectID   string
+	defaultProjectName string
+	// credential plugin for users of generated kubeconfigs, if it is nil
+	// kubeconfigExecErr tells why the provider token can't be read outside
+	// the provider
+	kubeconfigExec    *execConfig
+	kubeconfigExecErr error
 }
 
 // Provider returns a schema.Provider for MetaKube.
@@ -38,8 +59,8 @@ func Provider() *schema.Provider {
 			"host": {
 				Type:        schema.TypeString,
 				Optional:    true,
-				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_HOST", "https://metakube.syseleven.de"),
-				Description: "The hostname of MetaKube API (in form of URI)",
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_HOST", ""),
+				Description: "The hostname of MetaKube API (in form of URI), defaults to " + defaultHost,
 			},
 			"token": {
 				Type:        schema.TypeString,
@@ -54,8 +75,20 @@ func Provider() *schema.Provider {
 					[]string{
 						"METAKUBE_TOKEN_PATH",
 					},
-					"~/.metakube/auth"),
-				Description: "Path to the MetaKube authentication token, defaults to ~/.metakube/auth",
+					""),
+				Description: "Path to the MetaKube authentication token, defaults to " + defaultTokenPath,
+			},
+			"profile": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_PROFILE", ""),
+				Description: "Name of the context in the config file to take host, token and project from, defaults to its current-context",
+			},
+			"config_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_CONFIG", ""),
+				Description: "Path to the config file with connection profiles, defaults to " + defaultConfigPath,
 			},
 			"development": {
 				Type:        schema.TypeBool,
@@ -75,6 +108,255 @@ func Provider() *schema.Provider {
 				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_LOG_PATH", ""),
 				Description: "Path to store logs",
 			},
+			"trace_http": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_TRACE_HTTP", false),
+				Description: "Log all API requests and replies to log_path, with credentials redacted.",
+			},
+			"audit_log_path": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_AUDIT_LOG_PATH", ""),
+				Description: "File to append a JSON line to for every API call that changes objects",
+			},
+			"read_only": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_READ_ONLY", false),
+				Description: "Fail all API calls that would change objects, reading keeps working.",
+			},
+			"requests_per_second": {
+				Type:         schema.TypeFloat,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("METAKUBE_REQUESTS_PER_SECOND", 0.0),
+				ValidateFunc: validation.FloatAtLeast(0),
+				Description:  "Maximum number of API requests per second, 0 means no limit",
+			},
+			"max_concurrent_requests": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("METAKUBE_MAX_CONCURRENT_REQUESTS", 0),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of API requests in flight at once, 0 means no limit",
+			},
+			"ca_file": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_CA_FILE", ""),
+				Description: "Path to a PEM encoded CA bundle used to verify the API server certificate, in addition to the system roots",
+			},
+			"ca_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "PEM encoded CA bundle used to verify the API server certificate, in addition to the system roots",
+			},
+			"client_cert": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("METAKUBE_CLIENT_CERT", ""),
+				RequiredWith: []string{"client_key"},
+				Description:  "PEM encoded client certificate, or a path to it, for TLS client authentication",
+			},
+			"client_key": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				DefaultFunc:  schema.EnvDefaultFunc("METAKUBE_CLIENT_KEY", ""),
+				RequiredWith: []string{"client_cert"},
+				Description:  "PEM encoded client certificate key, or a path to it, for TLS client authentication",
+			},
+			"insecure_skip_verify": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("METAKUBE_INSECURE_SKIP_VERIFY", false),
+				Description: "Don't verify the API server certificate. Use for testing only",
+			},
+			"proxy_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "URL of the proxy used for API requests, defaults to HTTPS_PROXY and HTTP_PROXY environment variables",
+			},
+			"no_proxy": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Comma separated list of hosts to connect to without proxy, defaults to NO_PROXY environment variable",
+			},
+			"max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("METAKUBE_MAX_RETRIES", 5),
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Maximum number of retries of API requests failed because of temporary errors",
+			},
+			"retry_max_wait": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("METAKUBE_RETRY_MAX_WAIT", 30),
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Maximum time in seconds to wait between retries of API requests",
+			},
+			"oidc": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				MaxItems:      1,
+				Description:   "Obtain and refresh tokens from an OpenID Connect issuer instead of using a static token",
+				ConflictsWith: []string{"exec"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"issuer_url": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "URL of the OpenID Connect issuer",
+						},
+						"client_id": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "OAuth 2.0 client identifier",
+						},
+						"client_secret": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "OAuth 2.0 client secret, leave empty for public clients",
+						},
+						"refresh_token": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Sensitive:   true,
+							DefaultFunc: schema.EnvDefaultFunc("METAKUBE_OIDC_REFRESH_TOKEN", ""),
+							Description: "Refresh token used to obtain new tokens",
+						},
+						"device_flow": {
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+							Description: "Use the device authorization flow when no valid refresh token is available, the login URL and code are logged as a warning",
+						},
+						"scopes": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Description: "Scopes requested in the device authorization flow",
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+					},
+				},
+			},
+			"exec": {
+				Type:          schema.TypeList,
+				Optional:      true,
+				MaxItems:      1,
+				Description:   "Obtain tokens by running a credential plugin, like kubectl's ExecCredential plugins",
+				ConflictsWith: []string{"oidc"},
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"command": {
+							Type:        schema.TypeString,
+							Required:    true,
+							Description: "Command to execute",
+						},
+						"args": {
+							Type:        schema.TypeList,
+							Optional:    true,
+							Description: "Arguments passed to the command",
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"env": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Description: "Environment variables set for the command",
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"api_version": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     execDefaultAPIVersion,
+							Description: "API version of the ExecCredential object returned by the command",
+						},
+					},
+				},
+			},
+			"tracing": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Export OpenTelemetry traces of all operations and API calls",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"exporter": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      tracingExporterOTLP,
+							ValidateFunc: validation.StringInSlice([]string{tracingExporterOTLP, tracingExporterFile}, false),
+							Description:  "Where to export spans to, 'otlp' sends them to an OTLP/HTTP collector, 'file' writes them to file_path as JSON",
+						},
+						"endpoint": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "URL of the OTLP/HTTP collector, defaults to the standard OTEL_EXPORTER_OTLP_* environment variables",
+						},
+						"headers": {
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Sensitive:   true,
+							Description: "Headers sent to the OTLP/HTTP collector",
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+						},
+						"file_path": {
+							Type:        schema.TypeString,
+							Optional:    true,
+							Description: "File the spans are appended to when using the 'file' exporter",
+						},
+					},
+				},
+			},
+			"project_id": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("METAKUBE_PROJECT_ID", ""),
+				ConflictsWith: []string{"project_name"},
+				Description:   "Project of resources that don't set project_id or project_name",
+			},
+			"project_name": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("METAKUBE_PROJECT_NAME", ""),
+				ConflictsWith: []string{"project_id"},
+				Description:   "Name of the project of resources that don't set project_id or project_name",
+			},
+			"default_labels": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Labels added to all clusters and node deployments, labels set on a resource take precedence",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"labels": {
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem: &schema.Schema{
+								Type: schema.TypeString,
+							},
+							ValidateFunc: func(v interface{}, k string) (strings []string, errors []error) {
+								for key := range v.(map[string]interface{}) {
+									if metakubeResourceSystemLabelOrTag(key) {
+										errors = append(errors, fmt.Errorf("'%s' contains reserved string and can't be used", key))
+									}
+								}
+								return
+							},
+						},
+					},
+				},
+			},
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
@@ -90,9 +372,17 @@ func Provider() *schema.Provider {
 			"metakube_k8s_version": dataSourceMetakubeK8sClusterVersion(),
 			"metakube_sshkey":      dataSourceMetakubeSSHKey(),
 			"metakube_project":     dataSourceMetakubeProject(),
+			"metakube_kubeconfig":  dataSourceMetakubeKubeconfig(),
 		},
 	}
 
+	for name, r := range p.ResourcesMap {
+		instrumentResource(name, r)
+	}
+	for name, r := range p.DataSourcesMap {
+		instrumentResource(name, r)
+	}
+
 	// copying stderr because of https://github.com/hashicorp/go-plugin/issues/93
 	// as an example the standard log pkg points to the "old" stderr
 	stderr := os.Stderr
@@ -114,29 +404,84 @@ func configure(d *schema.ResourceData, terraformVersion string, fd *os.File) (in
 	var (
 		k                metakubeProviderMeta
 		diagnostics, tmp diag.Diagnostics
+		src              tokenSource
+		trace            *zap.Logger
 	)
 
-	k.log, tmp = newLogger(d, fd)
+	k.log, trace, tmp = newLogger(d, fd)
 	diagnostics = append(diagnostics, tmp...)
-	k.client, tmp = newClient(d.Get("host").(string))
+	k.debug = d.Get("debug").(bool) || d.Get("development").(bool)
+
+	conn, tmp := connectionSettings(d)
 	diagnostics = append(diagnostics, tmp...)
+	if tmp.HasError() {
+		return &k, diagnostics
+	}
+
+	base, tmp := newBaseTransport(d)
+	diagnostics = append(diagnostics, tmp...)
+	if tmp.HasError() {
+		return &k, diagnostics
+	}
+
+	src, tmp = newTokenSource(d, conn, base, k.log)
+	diagnostics = append(diagnostics, tmp...)
+	if src != nil {
+		k.auth = newTokenSourceAuth(src, terraformVersion)
+	}
 
-	k.auth, tmp = newAuth(d.Get("token").(string), d.Get("token_path").(string), terraformVersion)
+	k.tracerProvider, tmp = newTracerProvider(d)
+	diagnostics = append(diagnostics, tmp...)
+
+	k.projects = newProjectIndex()
+	k.defaultLabels = newDefaultLabels(d.Get("default_labels").([]interface{}))
+	k.defaultProjectID = conn.ProjectID
+	k.defaultProjectName = conn.ProjectName
+	k.kubeconfigExec, k.kubeconfigExecErr = newKubeconfigExec(d, conn)
+	k.limiter = newAPILimiter(d.Get("requests_per_second").(float64), d.Get("max_concurrent_requests").(int))
+	transport := newTransport(d, base, src, k.limiter, k.log, trace)
+	k.cache = newAPICache()
+	middleware := []clientMiddleware{authContextMiddleware, k.cache.middleware}
+	if path := d.Get("audit_log_path").(string); path != "" {
+		audit, err := newAuditLog(path, k.log)
+		if err != nil {
+			return &k, append(diagnostics, diag.Diagnostic{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't open audit log: %v", err),
+				AttributePath: cty.GetAttrPath("audit_log_path"),
+			})
+		}
+		middleware = append(middleware, audit.middleware)
+	}
+	if d.Get("read_only").(bool) {
+		middleware = append(middleware, readOnlyMiddleware)
+	}
+	if k.tracerProvider != nil {
+		// outermost, so that calls answered by the cache or rejected by
+		// read_only get a span as well
+		middleware = append(middleware, k.traceMiddleware)
+	}
+	k.client, tmp = newClient(conn.Host, transport, middleware...)
 	diagnostics = append(diagnostics, tmp...)
 
 	return &k, diagnostics
 }
 
-func newLogger(d *schema.ResourceData, fd *os.File) (*zap.SugaredLogger, diag.Diagnostics) {
+// newLogger returns the provider logger and, if trace_http is set, the logger
+// for HTTP traces, which writes to log_path only.
+func newLogger(d *schema.ResourceData, fd *os.File) (*zap.SugaredLogger, *zap.Logger, diag.Diagnostics) {
 	var (
-		ec    zapcore.EncoderConfig
-		cores []zapcore.Core
-		level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+		ec          zapcore.EncoderConfig
+		cores       []zapcore.Core
+		level       = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+		trace       *zap.Logger
+		diagnostics diag.Diagnostics
 	)
 
 	logDev := d.Get("development").(bool)
 	logDebug := d.Get("debug").(bool)
 	logPath := d.Get("log_path").(string)
+	traceHTTP := d.Get("trace_http").(bool)
 
 	if logDev || logDebug {
 		level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
@@ -159,21 +504,30 @@ func newLogger(d *schema.ResourceData, fd *os.File) (*zap.SugaredLogger, diag.Di
 		jsonEC.EncodeLevel = zapcore.LowercaseLevelEncoder
 		sink, _, err := zap.Open(logPath)
 		if err != nil {
-			return nil, diag.Diagnostics{{
+			return nil, nil, diag.Diagnostics{{
 				Severity:      diag.Error,
 				Summary:       fmt.Sprintf("Can't access location: %v", err),
 				AttributePath: cty.Path{cty.GetAttrStep{Name: "log_path"}},
 			}}
 		}
 		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(jsonEC), sink, level))
+		if traceHTTP {
+			trace = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(jsonEC), sink, zapcore.DebugLevel)).Named("http")
+		}
+	} else if traceHTTP {
+		diagnostics = append(diagnostics, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "HTTP tracing requires log_path to be set",
+			AttributePath: cty.Path{cty.GetAttrStep{Name: "trace_http"}},
+		})
 	}
 
 	cores = append(cores, zapcore.NewCore(zapcore.NewConsoleEncoder(ec), zapcore.AddSync(fd), level))
 	core := zapcore.NewTee(cores...)
-	return zap.New(core).Sugar(), nil
+	return zap.New(core).Sugar(), trace, diagnostics
 }
 
-func newClient(host string) (*k8client.MetaKubeAPI, diag.Diagnostics) {
+func newClient(host string, transport http.RoundTripper, middleware ...clientMiddleware) (*k8client.MetaKubeAPI, diag.Diagnostics) {
 	u, err := url.Parse(host)
 	if err != nil {
 		return nil, diag.Diagnostics{{
@@ -183,14 +537,26 @@ func newClient(host string) (*k8client.MetaKubeAPI, diag.Diagnostics) {
 		}}
 	}
 
-	return k8client.NewHTTPClientWithConfig(nil, &k8client.TransportConfig{
-		Host:     u.Host,
-		BasePath: u.Path,
-		Schemes:  []string{u.Scheme},
-	}), nil
+	rt := httptransport.New(u.Host, u.Path, []string{u.Scheme})
+	rt.Transport = transport
+	var ct runtime.ClientTransport = rt
+	for _, m := range middleware {
+		ct = m(ct)
+	}
+	return k8client.New(ct, nil), nil
 }
 
-func newAuth(token, tokenPath, terraformVersion string) (runtime.ClientAuthInfoWriter, diag.Diagnostics) {
+func newTokenSource(d *schema.ResourceData, conn profile, base http.RoundTripper, log *zap.SugaredLogger) (tokenSource, diag.Diagnostics) {
+	if v, ok := d.GetOk("oidc"); ok {
+		return newOIDCTokenSourceFromConfig(newOIDCConfig(v.([]interface{})), base, log)
+	}
+	if v, ok := d.GetOk("exec"); ok {
+		return newExecTokenSource(newExecConfig(v.([]interface{}))), nil
+	}
+	return newStaticTokenSource(conn.Token, conn.TokenPath)
+}
+
+func newStaticTokenSource(token, tokenPath string) (tokenSource, diag.Diagnostics) {
 	if token == "" && tokenPath != "" {
 		p, err := homedir.Expand(tokenPath)
 		if err != nil {
@@ -217,12 +583,76 @@ func newAuth(token, tokenPath, terraformVersion string) (runtime.ClientAuthInfoW
 		}}
 	}
 
-	auth := runtime.ClientAuthInfoWriterFunc(func(r runtime.ClientRequest, _ strfmt.Registry) error {
-		err := r.SetHeaderParam("Authorization", "Bearer "+token)
-		if err != nil {
-			return err
+	return staticTokenSource(token), nil
+}
+
+// newOIDCTokenSourceFromConfig returns the OIDC token source, the issuer is
+// requested through base to use the TLS and proxy settings of the provider.
+func newOIDCTokenSourceFromConfig(config oidcConfig, base http.RoundTripper, log *zap.SugaredLogger) (tokenSource, diag.Diagnostics) {
+	if _, err := url.Parse(config.issuerURL); err != nil {
+		return nil, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       fmt.Sprintf("Can't parse issuer url: %v", err),
+			AttributePath: cty.GetAttrPath("oidc").IndexInt(0).GetAttr("issuer_url"),
+		}}
+	}
+	if config.refreshToken == "" && !config.deviceFlow {
+		return nil, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Either refresh_token must be set or device_flow enabled",
+			AttributePath: cty.GetAttrPath("oidc").IndexInt(0),
+		}}
+	}
+
+	client := &http.Client{Transport: base, Timeout: 30 * time.Second}
+	return newOIDCTokenSource(config, client, log), nil
+}
+
+// tokenSource provides the bearer token for MetaKube API requests.
+type tokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(_ context.Context) (string, error) {
+	return string(s), nil
+}
+
+// tokenSourceAuth authenticates requests with the token of src. Getting a
+// token may wait for a device login, ctx is the context of the request, so
+// that it can be cancelled.
+type tokenSourceAuth struct {
+	src              tokenSource
+	terraformVersion string
+	ctx              context.Context
+}
+
+func newTokenSourceAuth(src tokenSource, terraformVersion string) runtime.ClientAuthInfoWriter {
+	return &tokenSourceAuth{src: src, terraformVersion: terraformVersion, ctx: context.Background()}
+}
+
+func (a *tokenSourceAuth) AuthenticateRequest(r runtime.ClientRequest, _ strfmt.Registry) error {
+	token, err := a.src.Token(a.ctx)
+	if err != nil {
+		return err
+	}
+	err = r.SetHeaderParam("Authorization", "Bearer "+token)
+	if err != nil {
+		return err
+	}
+	return r.SetHeaderParam("User-Agent", fmt.Sprintf("Terraform/%s", a.terraformVersion))
+}
+
+// authContextMiddleware passes the context of operations to the token source
+// authenticating them.
+func authContextMiddleware(next runtime.ClientTransport) runtime.ClientTransport {
+	return clientTransportFunc(func(op *runtime.ClientOperation) (interface{}, error) {
+		if a, ok := op.AuthInfo.(*tokenSourceAuth); ok && op.Context != nil {
+			auth := *a
+			auth.ctx = op.Context
+			op.AuthInfo = &auth
 		}
-		return r.SetHeaderParam("User-Agent", fmt.Sprintf("Terraform/%s", terraformVersion))
+		return next.Submit(op)
 	})
-	return auth, nil
 }
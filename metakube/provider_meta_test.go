@@ -0,0 +1,22 @@
+package metakube
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newTestProviderMeta returns provider meta with a client of a test server
+// serving handler, the middleware is installed in the client.
+func newTestProviderMeta(t *testing.T, handler http.Handler, middleware ...clientMiddleware) *metakubeProviderMeta {
+	t.Helper()
+	s := httptest.NewServer(handler)
+	t.Cleanup(s.Close)
+	client, diagnostics := newClient(s.URL, s.Client().Transport, middleware...)
+	if diagnostics.HasError() {
+		t.Fatalf("unexpected error: %v", diagnostics)
+	}
+	return &metakubeProviderMeta{client: client, log: zap.NewNop().Sugar()}
+}
@@ -0,0 +1,62 @@
+package metakube
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/runtime"
+)
+
+// prefixes of the ids of operations that change objects
+var mutatingOperationPrefixes = []string{
+	"create",
+	"patch",
+	"update",
+	"delete",
+	"assign",
+	"detach",
+	"bind",
+	"unbind",
+	"revoke",
+	"upgrade",
+}
+
+// readOnlyError is returned for operations blocked in read-only mode.
+type readOnlyError struct {
+	operation string
+	method    string
+	path      string
+}
+
+func (e *readOnlyError) Error() string {
+	return fmt.Sprintf("provider is read-only, refusing to call %s (%s %s)", e.operation, e.method, e.path)
+}
+
+func isMutatingOperation(op *runtime.ClientOperation) bool {
+	if op.Method != "" && op.Method != http.MethodGet && op.Method != http.MethodHead {
+		return true
+	}
+	id := strings.ToLower(op.ID)
+	for _, p := range mutatingOperationPrefixes {
+		if strings.HasPrefix(id, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyMiddleware fails all operations that would change objects,
+// without sending them to the API.
+func readOnlyMiddleware(next runtime.ClientTransport) runtime.ClientTransport {
+	return clientTransportFunc(func(op *runtime.ClientOperation) (interface{}, error) {
+		if isMutatingOperation(op) {
+			e := &readOnlyError{operation: op.ID, method: op.Method, path: op.PathPattern}
+			if req, err := newOperationRequest(op); err == nil {
+				e.path = req.path()
+			}
+			return nil, e
+		}
+		return next.Submit(op)
+	})
+}
@@ -0,0 +1,68 @@
+package metakube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-openapi/runtime"
+	"github.com/syseleven/go-metakube/client/project"
+	"github.com/syseleven/go-metakube/models"
+)
+
+func TestIsMutatingOperation(t *testing.T) {
+	testCases := []struct {
+		id     string
+		method string
+		want   bool
+	}{
+		{"getClusterV2", http.MethodGet, false},
+		{"listProjects", http.MethodGet, false},
+		{"createClusterV2", http.MethodPost, true},
+		{"patchClusterV2", http.MethodPatch, true},
+		{"deleteSSHKey", http.MethodDelete, true},
+		{"assignSSHKeyToClusterV2", http.MethodPut, true},
+		{"detachSSHKeyFromClusterV2", http.MethodDelete, true},
+		{"bindUserToRoleV2", http.MethodPost, true},
+		{"unbindUserFromRoleBindingV2", http.MethodDelete, true},
+		{"createOIDCKubeconfig", http.MethodGet, true},
+		{"restartMachineDeployment", http.MethodPost, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.id, func(t *testing.T) {
+			if got := isMutatingOperation(&runtime.ClientOperation{ID: tc.id, Method: tc.method}); got != tc.want {
+				t.Fatalf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	var requests int32
+	client := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}), readOnlyMiddleware).client
+
+	_, err := client.Project.ListSSHKeys(project.NewListSSHKeysParams().WithContext(context.Background()).WithProjectID("p1"), nil)
+	if err != nil {
+		t.Fatalf("reading should not be blocked: %v", err)
+	}
+
+	p := project.NewCreateSSHKeyParams().WithContext(context.Background()).WithProjectID("p1").WithKey(&models.SSHKey{Name: "key"})
+	_, err = client.Project.CreateSSHKey(p, nil)
+	var e *readOnlyError
+	if !errors.As(err, &e) {
+		t.Fatalf("want read-only error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "createSSHKey") || !strings.Contains(err.Error(), "/api/v1/projects/p1/sshkeys") {
+		t.Fatalf("error should name the blocked operation, got %q", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("want 1 request sent, got %d", got)
+	}
+}
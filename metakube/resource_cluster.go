@@ -36,12 +36,8 @@ func metakubeResourceCluster() *schema.Resource {
 		},
 
 		Schema: map[string]*schema.Schema{
-			"project_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Reference project identifier",
-			},
+			"project_id":   metakubeResourceProjectIDSchema(),
+			"project_name": metakubeResourceProjectNameSchema(),
 			"dc_name": {
 				Type:        schema.TypeString,
 				Required:    true,
@@ -73,6 +69,14 @@ func metakubeResourceCluster() *schema.Resource {
 					return
 				},
 			},
+			"labels_all": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "All labels of the cluster, including the provider default labels",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
 			"sshkeys": {
 				Type:        schema.TypeSet,
 				Optional:    true,
@@ -101,10 +105,52 @@ func metakubeResourceCluster() *schema.Resource {
 				Computed:    true,
 				Description: "Deletion timestamp",
 			},
+			"wait_for": {
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Description: "Readiness check on create and update",
+				Elem: &schema.Resource{
+					Schema: metakubeResourceClusterWaitForFields(),
+				},
+			},
+			"health": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Health of the cluster components, refreshed on every read",
+				Elem: &schema.Resource{
+					Schema: metakubeResourceClusterHealthFields(),
+				},
+			},
+			"admin_token_rotation_trigger": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Revokes the admin token whenever the value changes",
+			},
+			"viewer_token_rotation_trigger": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Revokes the viewer token whenever the value changes, viewer kubeconfigs are not provided by the API",
+			},
+			"fetch_kubeconfigs": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      fetchKubeconfigsAll,
+				ValidateFunc: validation.StringInSlice([]string{fetchKubeconfigsAll, fetchKubeconfigsAdmin, fetchKubeconfigsNone}, false),
+				Description:  "Kubeconfigs fetched on read, one of all, admin or none",
+			},
 			"kube_config": {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"kube_config_attributes": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Connection settings parsed from kube_config",
+				Elem: &schema.Resource{
+					Schema: metakubeResourceClusterKubeconfigAttributesFields(),
+				},
+			},
 			"oidc_kube_config": {
 				Type:     schema.TypeString,
 				Computed: true,
@@ -114,9 +160,16 @@ func metakubeResourceCluster() *schema.Resource {
 				Computed: true,
 			},
 		},
-		CustomizeDiff: customdiff.All(customdiff.ForceNewIfChange(
-			"spec.0.version",
-			metakubeResourceClusterIsVersionDowngraded)),
+		CustomizeDiff: customdiff.Sequence(
+			customdiff.All(
+				customdiff.ForceNewIfChange("spec.0.version", metakubeResourceClusterIsVersionDowngraded),
+				labelsAllDiff("labels"),
+				metakubeResourceProjectDiff,
+				metakubeResourceClusterTokenRotationDiff,
+			),
+			// Not part of All, which would join the error and lose its attribute path.
+			metakubeResourceClusterValidateDiff,
+		),
 	}
 }
 
@@ -138,11 +191,14 @@ func metakubeResourceClusterIsVersionDowngraded(_ context.Context, old, new, met
 
 func metakubeResourceClusterCreate(ctx context.Context, d *schema.ResourceData, m interface{}) (diagnostics diag.Diagnostics) {
 	meta := m.(*metakubeProviderMeta)
-	retDiags := metakubeResourceClusterValidateClusterFields(ctx, d, meta)
+	projectID, retDiags := metakubeResourceProjectID(ctx, d, meta)
+	if retDiags.HasError() {
+		return retDiags
+	}
 	spec := d.Get("spec").([]interface{})
 	dcname := d.Get("dc_name").(string)
 	clusterSpec := metakubeResourceClusterExpandSpec(spec, dcname, func(_ string) bool { return true })
-	clusterLabels := metakubeResourceClusterLabels(d)
+	clusterLabels := metakubeResourceClusterLabels(d, meta.defaultLabels)
 	createClusterSpec := &models.CreateClusterSpec{
 		Cluster: &models.Cluster{
 			Name:   d.Get("name").(string),
@@ -177,51 +233,41 @@ func metakubeResourceClusterCreate(ctx context.Context, d *schema.ResourceData,
 	}
 
 	sshkeys := metakubeResourceClusterSSHKeys(d)
-	if len(sshkeys) > 0 && !d.Get("spec.0.enable_ssh_agent").(bool) {
-		return append(retDiags, diag.Diagnostic{
-			Severity:      diag.Error,
-			AttributePath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("enable_ssh_agent"),
-			Summary:       "SSH Agent must be enabled in order to automatically manage ssh keys",
-		})
-	}
 
 	if len(retDiags) > 0 {
 		return retDiags
 	}
 
-	projectID := d.Get("project_id").(string)
-	p := project.NewCreateClusterV2Params().WithProjectID(projectID).WithBody(createClusterSpec)
+	p := project.NewCreateClusterV2Params().WithContext(ctx).WithProjectID(projectID).WithBody(createClusterSpec)
 	r, err := meta.client.Project.CreateClusterV2(p, meta.auth)
 	if err != nil {
-		return diag.Errorf("unable to create cluster for project '%s': %s", projectID, stringifyResponseError(err))
+		return diagFromAPIError(err, fmt.Sprintf("unable to create cluster for project '%s'", projectID), cty.GetAttrPath("spec"))
 	}
 	d.SetId(r.Payload.ID)
 
-	if err := assignSSHKeysToCluster(projectID, r.Payload.ID, sshkeys, meta); err != nil {
+	if err := assignSSHKeysToCluster(ctx, projectID, r.Payload.ID, sshkeys, meta); err != nil {
 		return diag.FromErr(err)
 	}
 
-	if err := metakubeResourceClusterWaitForReady(ctx, meta, d.Timeout(schema.TimeoutCreate), projectID, d.Id()); err != nil {
-		// In case of timeout, we still want to return the cluster resource
-		retDiags = append(retDiags, metakubeResourceClusterRead(ctx, d, m)...)
-		retDiags = append(retDiags, diag.Diagnostic{
-			Severity: diag.Error,
-			Summary:  fmt.Sprintf("Cluster '%s' is not ready: %v", r.Payload.ID, err),
-		})
-		return retDiags
+	if waitFor := metakubeResourceClusterExpandWaitFor(d.Get("wait_for").([]interface{})); waitFor.enabled {
+		if err := metakubeResourceClusterWaitForReady(ctx, meta, d.Timeout(schema.TimeoutCreate), projectID, d.Id(), waitFor.components); err != nil {
+			// In case of timeout, we still want to return the cluster resource
+			retDiags = append(retDiags, metakubeResourceClusterRead(ctx, d, m)...)
+			return append(retDiags, notReadyDiagnostics(err, waitFor.severity, fmt.Sprintf("Cluster '%s' is not ready", r.Payload.ID))...)
+		}
 	}
 
 	return metakubeResourceClusterRead(ctx, d, m)
 }
 
-func metakubeResourceClusterLabels(d *schema.ResourceData) map[string]string {
+func metakubeResourceClusterLabels(d *schema.ResourceData, defaultLabels map[string]string) map[string]string {
 	labels := make(map[string]string)
 	if m, ok := d.Get("labels").(map[string]interface{}); ok {
 		for k, v := range m {
 			labels[k] = v.(string)
 		}
 	}
-	return labels
+	return mergeDefaultLabels(defaultLabels, labels)
 }
 
 func metakubeResourceClusterSSHKeys(d *schema.ResourceData) []string {
@@ -237,7 +283,7 @@ func metakubeResourceClusterFindDatacenterByName(ctx context.Context, k *metakub
 	p := datacenter.NewListDatacentersV2Params().WithContext(ctx)
 	r, err := k.client.Datacenter.ListDatacentersV2(p, k.auth)
 	if err != nil {
-		return nil, diag.Errorf("Can't list datacenters: %s", stringifyResponseError(err))
+		return nil, diagFromAPIError(err, "Can't list datacenters", nil)
 	}
 
 	available := make([]string, 0)
@@ -301,12 +347,12 @@ func metakubeResourceClusterRead(ctx context.Context, d *schema.ResourceData, m
 			d.SetId("")
 			return nil
 		}
-		k.log.Debugf("found cluster in project '%s'", projectID)
+		k.logger(ctx).Debugf("found cluster in project '%s'", projectID)
 	}
 	p := project.NewGetClusterV2Params().WithContext(ctx).WithProjectID(projectID).WithClusterID(d.Id())
 	r, err := k.client.Project.GetClusterV2(p, k.auth)
 	if metakubeResourceClusterResponseNotFound(err) {
-		k.log.Infof("removing cluster '%s', could not find the resource", d.Id())
+		k.logger(ctx).Infof("removing cluster '%s', could not find the resource", d.Id())
 		d.SetId("")
 		return nil
 	}
@@ -316,15 +362,16 @@ func metakubeResourceClusterRead(ctx context.Context, d *schema.ResourceData, m
 		// the GET request returns 500 http code instead of 404, probably it's a bug
 		// because of that manual action to clean terraform state file is required
 
-		k.log.Debugf("get cluster: %v", err)
-		return diag.Errorf("unable to get cluster '%s/%s': %s", projectID, d.Id(), stringifyResponseError(err))
+		k.logger(ctx).Debugf("get cluster: %v", err)
+		return diagFromAPIError(err, fmt.Sprintf("unable to get cluster '%s/%s'", projectID, d.Id()), nil)
 	}
 
 	_ = d.Set("project_id", projectID)
 	_ = d.Set("dc_name", r.Payload.Spec.Cloud.DatacenterName)
 	_ = d.Set("name", r.Payload.Name)
+	_ = d.Set("labels_all", r.Payload.Labels)
 	if len(r.Payload.Labels) > 0 {
-		if err := d.Set("labels", r.Payload.Labels); err != nil {
+		if err := d.Set("labels", labelsWithoutDefaults(r.Payload.Labels, d.Get("labels"), k.defaultLabels)); err != nil {
 			return diag.Diagnostics{{
 				Severity:      diag.Error,
 				Summary:       "Invalid value",
@@ -355,48 +402,20 @@ func metakubeResourceClusterRead(ctx context.Context, d *schema.ResourceData, m
 		d.Set("sshkeys", keys)
 	}
 
-	if conf, err := metakubeClusterUpdateKubeconfig(ctx, k, projectID, d.Id()); err != nil {
-		return diag.Diagnostics{{
+	var retDiags diag.Diagnostics
+	if health, err := metakubeResourceClusterGetHealth(ctx, k, projectID, d.Id()); err != nil {
+		retDiags = append(retDiags, diag.Diagnostic{
 			Severity:      diag.Warning,
-			Summary:       fmt.Sprintf("could not update kubeconfig: %v", err),
-			AttributePath: cty.GetAttrPath("kube_config"),
-		}}
+			Summary:       fmt.Sprintf("could not update health: %v", err),
+			AttributePath: cty.GetAttrPath("health"),
+		})
 	} else {
-		err = d.Set("kube_config", conf)
-		if err != nil {
-			k.log.Error(err)
-		}
+		_ = d.Set("health", metakubeResourceClusterFlattenHealth(health))
 	}
 
-	if _, ok := d.GetOk("spec.0.syseleven_auth.0.realm"); ok {
-		if conf, err := metakubeClusterUpdateOIDCKubeconfig(ctx, k, projectID, d.Id()); err != nil {
-			return diag.Diagnostics{{
-				Severity:      diag.Warning,
-				Summary:       fmt.Sprintf("could not update OIDC kubeconfig: %s", stringifyResponseError(err)),
-				AttributePath: cty.GetAttrPath("oidc_kube_config"),
-			}}
-		} else {
-			err = d.Set("oidc_kube_config", conf)
-			if err != nil {
-				k.log.Error(err)
-			}
-		}
-
-		if conf, err := metakubeClusterUpdateKubeloginKubeconfig(ctx, k, projectID, d.Id()); err != nil {
-			return diag.Diagnostics{{
-				Severity:      diag.Warning,
-				Summary:       fmt.Sprintf("could not update kubelogin kubeconfig: %v", err),
-				AttributePath: cty.GetAttrPath("kube_login_kube_config"),
-			}}
-		} else {
-			err = d.Set("kube_login_kube_config", conf)
-			if err != nil {
-				k.log.Error(err)
-			}
-		}
-	}
+	retDiags = append(retDiags, metakubeResourceClusterFetchKubeconfigs(ctx, d, k, projectID)...)
 
-	return nil
+	return retDiags
 }
 
 func metakubeClusterUpdateKubeconfig(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) (string, error) {
@@ -406,11 +425,19 @@ func metakubeClusterUpdateKubeconfig(ctx context.Context, k *metakubeProviderMet
 	kubeConfigParams.SetClusterID(clusterID)
 	ret, err := k.client.Project.GetClusterKubeconfigV2(kubeConfigParams, k.auth)
 	if err != nil {
-		return "", fmt.Errorf("failed to get kube_config: %s", stringifyResponseError(err))
+		return "", fmt.Errorf("failed to get kube_config: %w", newAPIError(err))
 	}
 	return string(ret.Payload), nil
 }
 
+func metakubeClusterKubeconfigAttributes(raw string) (*kubeconfigAttributes, error) {
+	c, err := parseKubeconfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	return c.attributes()
+}
+
 func metakubeClusterUpdateOIDCKubeconfig(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) (string, error) {
 	kubeConfigParams := project.NewGetOidcClusterKubeconfigV2Params()
 	kubeConfigParams.SetContext(ctx)
@@ -418,7 +445,7 @@ func metakubeClusterUpdateOIDCKubeconfig(ctx context.Context, k *metakubeProvide
 	kubeConfigParams.SetClusterID(clusterID)
 	ret, err := k.client.Project.GetOidcClusterKubeconfigV2(kubeConfigParams, k.auth)
 	if err != nil {
-		return "", fmt.Errorf("failed to get oidc_kube_config: %s", stringifyResponseError(err))
+		return "", fmt.Errorf("failed to get oidc_kube_config: %w", newAPIError(err))
 	}
 	return string(ret.Payload), nil
 }
@@ -430,58 +457,25 @@ func metakubeClusterUpdateKubeloginKubeconfig(ctx context.Context, k *metakubePr
 	kubeConfigParams.SetClusterID(clusterID)
 	ret, err := k.client.Project.GetKubeLoginClusterKubeconfigV2(kubeConfigParams, k.auth)
 	if err != nil {
-		return "", fmt.Errorf("failed to get kube_login_kube_config: %s", stringifyResponseError(err))
+		return "", fmt.Errorf("failed to get kube_login_kube_config: %w", newAPIError(err))
 	}
 	return string(ret.Payload), nil
 }
 
 func metakubeResourceClusterFindProjectID(ctx context.Context, id string, meta *metakubeProviderMeta) (string, error) {
-	res, err := meta.client.Project.ListProjects(project.NewListProjectsParams(), meta.auth)
-	if err != nil {
-		return "", fmt.Errorf("list projects: %v", err)
-	}
-
-	for _, project := range res.Payload {
-		ok, err := metakubeResourceClusterBelongsToProject(ctx, project.ID, id, meta)
-		if ok {
-			return project.ID, nil
-		}
-		if err != nil {
-			return "", err
-		}
-	}
-
-	meta.log.Infof("owner project for cluster with id '%s' not found", id)
-	return "", nil
-}
-
-func metakubeResourceClusterBelongsToProject(ctx context.Context, prj, id string, meta *metakubeProviderMeta) (bool, error) {
-	prms := project.NewListClustersV2Params().WithContext(ctx).WithProjectID(prj)
-	res, err := meta.client.Project.ListClustersV2(prms, meta.auth)
+	projectID, err := meta.projects.findCluster(ctx, id, meta)
 	if err != nil {
-		meta.log.Debugf("lookup owner project: list clusters: %v", err)
-		return false, fmt.Errorf("list clusters: %s", stringifyResponseError(err))
+		return "", err
 	}
-	for _, item := range res.Payload {
-		if item.ID == id {
-			return true, nil
-		}
+	if projectID == "" {
+		meta.logger(ctx).Infof("owner project for cluster with id '%s' not found", id)
 	}
-	return false, nil
+	return projectID, nil
 }
 
 func metakubeResourceClusterResponseNotFound(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	e, ok := err.(*project.GetClusterV2Default)
-	if !ok {
-		return false
-	}
-
 	// All api replies and errors, that nevertheless indicate cluster was deleted.
-	return e.Code() == http.StatusNotFound
+	return apiErrorIs(err, apiErrorNotFound)
 }
 
 func metakubeClusterGetAssignedSSHKeys(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) ([]string, error) {
@@ -489,7 +483,7 @@ func metakubeClusterGetAssignedSSHKeys(ctx context.Context, d *schema.ResourceDa
 	p := project.NewListSSHKeysAssignedToClusterV2Params().WithProjectID(projectID).WithClusterID(d.Id()).WithContext(ctx)
 	ret, err := k.client.Project.ListSSHKeysAssignedToClusterV2(p, k.auth)
 	if err != nil {
-		return nil, fmt.Errorf("List project keys error %v", stringifyResponseError(err))
+		return nil, fmt.Errorf("List project keys error %w", newAPIError(err))
 	}
 
 	var ids []string
@@ -579,27 +573,30 @@ func metakubeResourceClusterUpdate(ctx context.Context, d *schema.ResourceData,
 	k := m.(*metakubeProviderMeta)
 	projectID := d.Get("project_id").(string)
 
-	var retDiags diag.Diagnostics
-	if cluster, ok, err := metakubeGetCluster(ctx, projectID, d.Id(), k); err != nil {
+	if _, ok, err := metakubeGetCluster(ctx, projectID, d.Id(), k); err != nil {
 		return diag.FromErr(err)
 	} else if !ok {
 		// Indicate resource deleted.
 		d.SetId("")
 		return nil
-	} else if d.HasChange("spec.0.version") {
-		k.log.Debugf("validating version change")
-		retDiags = metakubeResourceClusterValidateVersionUpgrade(ctx, projectID, d.Get("spec.0.version").(string), cluster, k)
 	}
-	retDiags = append(retDiags, metakubeResourceClusterValidateClusterFields(ctx, d, k)...)
 
-	_, diagnostics := metakubeResourceClusterFindDatacenterByName(ctx, k, d)
-	// TODO: delete composed diagnostics, seems to be useless at the moment.
-	retDiags = append(retDiags, diagnostics...)
-	if len(retDiags) > 0 {
-		return retDiags
+	if _, diagnostics := metakubeResourceClusterFindDatacenterByName(ctx, k, d); len(diagnostics) > 0 {
+		return diagnostics
+	}
+
+	var retDiags diag.Diagnostics
+	if err := metakubeResourceClusterRotateToken(ctx, d, k, projectID, "admin_token_rotation_trigger", metakubeResourceClusterRevokeAdminToken); err != nil {
+		return diag.FromErr(err)
+	}
+	if d.HasChanges("admin_token_rotation_trigger", "fetch_kubeconfigs") {
+		retDiags = append(retDiags, metakubeResourceClusterFetchKubeconfigs(ctx, d, k, projectID)...)
+	}
+	if err := metakubeResourceClusterRotateToken(ctx, d, k, projectID, "viewer_token_rotation_trigger", metakubeResourceClusterRevokeViewerToken); err != nil {
+		return diag.FromErr(err)
 	}
 
-	if d.HasChanges("name", "labels", "spec") {
+	if d.HasChanges("name", "labels", "labels_all", "spec") {
 		if err := metakubeResourceClusterSendPatchReq(ctx, d, k); err != nil {
 			return diag.FromErr(err)
 		}
@@ -610,11 +607,13 @@ func metakubeResourceClusterUpdate(ctx context.Context, d *schema.ResourceData,
 		}
 	}
 
-	if err := metakubeResourceClusterWaitForReady(ctx, k, d.Timeout(schema.TimeoutUpdate), projectID, d.Id()); err != nil {
-		return diag.Errorf("cluster '%s' is not ready: %v", d.Id(), err)
+	if waitFor := metakubeResourceClusterExpandWaitFor(d.Get("wait_for").([]interface{})); waitFor.enabled {
+		if err := metakubeResourceClusterWaitForReady(ctx, k, d.Timeout(schema.TimeoutUpdate), projectID, d.Id(), waitFor.components); err != nil {
+			return append(retDiags, notReadyDiagnostics(err, waitFor.severity, fmt.Sprintf("cluster '%s' is not ready", d.Id()))...)
+		}
 	}
 
-	return nil
+	return retDiags
 }
 
 func metakubeResourceClusterSendPatchReq(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) error {
@@ -624,7 +623,7 @@ func metakubeResourceClusterSendPatchReq(ctx context.Context, d *schema.Resource
 	p.SetProjectID(projectID)
 	p.SetClusterID(d.Id())
 	name := d.Get("name").(string)
-	labels := metakubeResourceClusterGetLabelsChange(d)
+	labels := metakubeResourceClusterGetLabelsChange(d, k.defaultLabels)
 	clusterSpec := metakubeResourceClusterExpandSpec(d.Get("spec").([]interface{}), d.Get("dc_name").(string), func(k string) bool { return d.HasChange("spec.0." + k) })
 	p.SetPatch(map[string]interface{}{
 		"name":   name,
@@ -633,13 +632,14 @@ func metakubeResourceClusterSendPatchReq(ctx context.Context, d *schema.Resource
 	})
 
 	var patchedCluster *models.Cluster
-	err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutUpdate), func() *retry.RetryError {
+	err := retryContext(ctx, d.Timeout(schema.TimeoutUpdate), "patch cluster", func(ctx context.Context) *retry.RetryError {
 		patchResult, err := k.client.Project.PatchClusterV2(p, k.auth)
 		if err != nil {
-			if e, ok := err.(*project.PatchClusterV2Default); ok && e.Code() == http.StatusConflict {
-				return retry.RetryableError(fmt.Errorf("cluster patch conflict: %v", err))
+			e := newAPIError(err)
+			if e.kind == apiErrorConflict {
+				return retry.RetryableError(fmt.Errorf("cluster patch conflict: %w", e))
 			}
-			return retry.NonRetryableError(fmt.Errorf("patch cluster '%s': %v", d.Id(), stringifyResponseError(err)))
+			return retry.NonRetryableError(fmt.Errorf("patch cluster '%s': %w", d.Id(), e))
 		}
 		patchedCluster = patchResult.GetPayload()
 		return nil
@@ -653,30 +653,15 @@ func metakubeResourceClusterSendPatchReq(ctx context.Context, d *schema.Resource
 		// otherwise, if the cluster had labels before that were all removed by the patch, remnants
 		// (empty labels) would remain in the data, showing up as a permanent difference on subsequent runs
 		_ = d.Set("labels", nil)
+		_ = d.Set("labels_all", nil)
 	}
 
 	return nil
 }
 
-func metakubeResourceClusterGetLabelsChange(d *schema.ResourceData) map[string]interface{} {
-	oldLabels, newLabels := d.GetChange("labels")
-	var oldLabelsMap, newLabelsMap map[string]interface{}
-	if oldLabels != nil {
-		oldLabelsMap = oldLabels.(map[string]interface{})
-	}
-	if newLabels != nil {
-		newLabelsMap = newLabels.(map[string]interface{})
-	} else {
-		newLabelsMap = make(map[string]interface{})
-	}
-
-	for k := range oldLabelsMap {
-		if _, ok := newLabelsMap[k]; !ok {
-			newLabelsMap[k] = nil
-		}
-	}
-
-	return newLabelsMap
+func metakubeResourceClusterGetLabelsChange(d *schema.ResourceData, defaultLabels map[string]string) map[string]interface{} {
+	old := labelsAllBefore(d, "labels")
+	return labelsPatch(old, labelsAll(old, d.Get("labels"), defaultLabels))
 }
 
 func updateClusterSSHKeys(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) error {
@@ -705,65 +690,78 @@ func updateClusterSSHKeys(ctx context.Context, d *schema.ResourceData, k *metaku
 
 	for _, id := range unassigned {
 		p := project.NewDetachSSHKeyFromClusterV2Params()
+		p.SetContext(ctx)
 		p.SetProjectID(projectID)
 		p.SetClusterID(d.Id())
 		p.SetKeyID(id)
 		_, err := k.client.Project.DetachSSHKeyFromClusterV2(p, k.auth)
 		if err != nil {
-			if e, ok := err.(*project.DetachSSHKeyFromClusterV2Default); ok && e.Code() == http.StatusNotFound {
+			if apiErrorIs(err, apiErrorNotFound) {
 				continue
 			}
-			return fmt.Errorf("failed to unassign sshkey: %s", stringifyResponseError(err))
+			return fmt.Errorf("failed to unassign sshkey: %w", newAPIError(err))
 		}
 	}
 
-	if err := assignSSHKeysToCluster(projectID, d.Id(), assign, k); err != nil {
+	if err := assignSSHKeysToCluster(ctx, projectID, d.Id(), assign, k); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func assignSSHKeysToCluster(projectID, clusterID string, sshkeyIDs []string, k *metakubeProviderMeta) error {
+func assignSSHKeysToCluster(ctx context.Context, projectID, clusterID string, sshkeyIDs []string, k *metakubeProviderMeta) error {
 	for _, id := range sshkeyIDs {
-		p := project.NewAssignSSHKeyToClusterV2Params().WithProjectID(projectID).WithClusterID(clusterID).WithKeyID(id)
+		p := project.NewAssignSSHKeyToClusterV2Params().WithContext(ctx).WithProjectID(projectID).WithClusterID(clusterID).WithKeyID(id)
 		_, err := k.client.Project.AssignSSHKeyToClusterV2(p, k.auth)
 		if err != nil {
-			return fmt.Errorf("can't assign sshkeys to cluster '%s': %v", clusterID, err)
+			return fmt.Errorf("can't assign sshkeys to cluster '%s': %w", clusterID, newAPIError(err))
 		}
 	}
 
 	return nil
 }
 
-func metakubeResourceClusterWaitForReady(ctx context.Context, k *metakubeProviderMeta, timeout time.Duration, projectID, clusterID string) error {
-	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+func metakubeResourceClusterGetHealth(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) (*models.ClusterHealth, error) {
+	p := project.NewGetClusterHealthV2Params()
+	p.SetContext(ctx)
+	p.SetProjectID(projectID)
+	p.SetClusterID(clusterID)
 
-		p := project.NewGetClusterHealthV2Params()
-		p.SetContext(ctx)
-		p.SetProjectID(projectID)
-		p.SetClusterID(clusterID)
+	r, err := k.client.Project.GetClusterHealthV2(p, k.auth)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cluster '%s' health: %w", clusterID, newAPIError(err))
+	}
+	return r.Payload, nil
+}
 
-		r, err := k.client.Project.GetClusterHealthV2(p, k.auth)
+// metakubeResourceClusterWaitForReady waits for the given components of the
+// cluster to be up, for all components if none are given.
+func metakubeResourceClusterWaitForReady(ctx context.Context, k *metakubeProviderMeta, timeout time.Duration, projectID, clusterID string, components []string) error {
+	var last *models.ClusterHealth
+	err := retryContext(ctx, timeout, "wait for cluster health", func(ctx context.Context) *retry.RetryError {
+		health, err := metakubeResourceClusterGetHealth(ctx, k, projectID, clusterID)
 		if err != nil {
-			return retry.RetryableError(fmt.Errorf("unable to get cluster '%s' health: %s", clusterID, stringifyResponseError(err)))
+			return retry.RetryableError(err)
 		}
+		last = health
 
-		const up models.HealthStatus = 1
-
-		if r.Payload.Apiserver == up &&
-			r.Payload.CloudProviderInfrastructure == up &&
-			r.Payload.Controller == up &&
-			r.Payload.Etcd == up &&
-			r.Payload.MachineController == up &&
-			r.Payload.Scheduler == up &&
-			r.Payload.UserClusterControllerManager == up {
+		if metakubeClusterHealthReady(health, components) {
 			return nil
 		}
 
-		k.log.Debugf("waiting for cluster '%s' to be ready, %+v", clusterID, r.Payload)
+		k.logger(ctx).Debugf("waiting for cluster '%s' to be ready, %+v", clusterID, health)
 		return retry.RetryableError(fmt.Errorf("waiting for cluster '%s' to be ready", clusterID))
 	})
+	if err != nil {
+		return &notReadyError{
+			err:        err,
+			health:     last,
+			components: components,
+			events:     metakubeClusterEvents(ctx, k, projectID, clusterID),
+		}
+	}
+	return nil
 }
 
 func metakubeResourceClusterDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -775,47 +773,42 @@ func metakubeResourceClusterDelete(ctx context.Context, d *schema.ResourceData,
 	p.SetClusterID(d.Id())
 
 	deleteSent := false
-	err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutDelete), func() *retry.RetryError {
+	err := retryContext(ctx, d.Timeout(schema.TimeoutDelete), "delete cluster", func(ctx context.Context) *retry.RetryError {
 		if !deleteSent {
+			p.SetContext(ctx)
 			_, err := k.client.Project.DeleteClusterV2(p, k.auth)
 			if err != nil {
-				if e, ok := err.(*project.DeleteClusterV2Default); ok {
-					if e.Code() == http.StatusConflict {
-						return retry.RetryableError(err)
-					}
-					if e.Code() == http.StatusNotFound {
-						return nil
-					}
-				}
-				if _, ok := err.(*project.DeleteClusterV2Forbidden); ok {
+				switch e := newAPIError(err); e.kind {
+				case apiErrorConflict, apiErrorTransient:
+					return retry.RetryableError(e)
+				case apiErrorNotFound, apiErrorForbidden:
 					return nil
+				default:
+					return retry.NonRetryableError(fmt.Errorf("unable to delete cluster '%s': %w", d.Id(), e))
 				}
-				return retry.NonRetryableError(fmt.Errorf("unable to delete cluster '%s': %s", d.Id(), stringifyResponseError(err)))
 			}
 			deleteSent = true
 		}
 		p := project.NewGetClusterV2Params()
 
+		p.SetContext(ctx)
 		p.SetProjectID(projectID)
 		p.SetClusterID(d.Id())
 
 		r, err := k.client.Project.GetClusterV2(p, k.auth)
 		if err != nil {
-			if e, ok := err.(*project.GetClusterV2Default); ok {
-				if e.Code() == http.StatusNotFound {
-					k.log.Debugf("cluster '%s' has been destroyed, returned http code: %d", d.Id(), e.Code())
-					return nil
-				} else if e.Code() == http.StatusInternalServerError {
-					return retry.RetryableError(err)
-				}
-			}
-			if _, ok := err.(*project.GetClusterV2Forbidden); ok {
-				return retry.RetryableError(err)
+			e := newAPIError(err)
+			switch {
+			case e.kind == apiErrorNotFound:
+				k.logger(ctx).Debugf("cluster '%s' has been destroyed, returned http code: %d", d.Id(), e.status)
+				return nil
+			case e.kind == apiErrorForbidden, e.kind == apiErrorTransient, e.status == http.StatusInternalServerError:
+				return retry.RetryableError(e)
 			}
-			return retry.NonRetryableError(fmt.Errorf("unable to get cluster '%s': %v", d.Id(), err))
+			return retry.NonRetryableError(fmt.Errorf("unable to get cluster '%s': %w", d.Id(), e))
 		}
 
-		k.log.Debugf("cluster '%s' deletion in progress, deletionTimestamp: %s",
+		k.logger(ctx).Debugf("cluster '%s' deletion in progress, deletionTimestamp: %s",
 			d.Id(), r.Payload.DeletionTimestamp.String())
 		return retry.RetryableError(fmt.Errorf("cluster '%s' deletion in progress", d.Id()))
 	})
@@ -828,7 +821,7 @@ func metakubeResourceClusterDelete(ctx context.Context, d *schema.ResourceData,
 func getProject(meta *metakubeProviderMeta, id string) (*models.Project, error) {
 	ret, err := meta.client.Project.GetProject(project.NewGetProjectParams().WithProjectID(id), meta.auth)
 	if err != nil {
-		return nil, fmt.Errorf(stringifyResponseError(err))
+		return nil, newAPIError(err)
 	}
 	return ret.Payload, nil
 }
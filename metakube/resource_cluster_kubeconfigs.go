@@ -0,0 +1,106 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+const (
+	fetchKubeconfigsAll   = "all"
+	fetchKubeconfigsAdmin = "admin"
+	fetchKubeconfigsNone  = "none"
+)
+
+// clusterKubeconfigFetch is the result of fetching the kubeconfig of an
+// attribute.
+type clusterKubeconfigFetch struct {
+	attribute string
+	name      string
+	get       func(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) (string, error)
+
+	value string
+	err   error
+}
+
+// metakubeResourceClusterFetchKubeconfigs fetches the kubeconfigs selected by
+// fetch_kubeconfigs concurrently. A failed fetch is a warning on its attribute
+// and does not keep the other kubeconfigs from being set.
+func metakubeResourceClusterFetchKubeconfigs(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta, projectID string) diag.Diagnostics {
+	mode := d.Get("fetch_kubeconfigs").(string)
+	if mode == "" {
+		// states written before fetch_kubeconfigs existed have no value
+		mode = fetchKubeconfigsAll
+	}
+	if mode == fetchKubeconfigsNone {
+		return nil
+	}
+	fetches := []*clusterKubeconfigFetch{
+		{attribute: "kube_config", name: "kubeconfig", get: metakubeClusterUpdateKubeconfig},
+	}
+	if _, ok := d.GetOk("spec.0.syseleven_auth.0.realm"); ok && mode != fetchKubeconfigsAdmin {
+		fetches = append(fetches,
+			&clusterKubeconfigFetch{attribute: "oidc_kube_config", name: "OIDC kubeconfig", get: metakubeClusterUpdateOIDCKubeconfig},
+			&clusterKubeconfigFetch{attribute: "kube_login_kube_config", name: "kubelogin kubeconfig", get: metakubeClusterUpdateKubeloginKubeconfig},
+		)
+	}
+
+	var wg sync.WaitGroup
+	for _, f := range fetches {
+		wg.Add(1)
+		go func(f *clusterKubeconfigFetch) {
+			defer wg.Done()
+			f.value, f.err = f.get(ctx, k, projectID, d.Id())
+		}(f)
+	}
+	wg.Wait()
+
+	var ret diag.Diagnostics
+	for _, f := range fetches {
+		if f.err != nil {
+			ret = append(ret, diag.Diagnostic{
+				Severity:      diag.Warning,
+				Summary:       fmt.Sprintf("could not update %s: %v", f.name, f.err),
+				AttributePath: cty.GetAttrPath(f.attribute),
+			})
+			continue
+		}
+		if f.attribute == "kube_config" {
+			ret = append(ret, metakubeResourceClusterSetAdminKubeconfig(ctx, d, k, f.value)...)
+			continue
+		}
+		if d.Get(f.attribute).(string) == f.value {
+			continue
+		}
+		if err := d.Set(f.attribute, f.value); err != nil {
+			k.logger(ctx).Error(err)
+		}
+	}
+	return ret
+}
+
+// metakubeResourceClusterSetAdminKubeconfig stores the admin kubeconfig and the
+// attributes parsed from it, unless they are unchanged. Parse errors are
+// returned as warnings.
+func metakubeResourceClusterSetAdminKubeconfig(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta, conf string) diag.Diagnostics {
+	if d.Get("kube_config").(string) == conf && len(d.Get("kube_config_attributes").([]interface{})) > 0 {
+		return nil
+	}
+	if err := d.Set("kube_config", conf); err != nil {
+		k.logger(ctx).Error(err)
+	}
+	attributes, err := metakubeClusterKubeconfigAttributes(conf)
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Warning,
+			Summary:       fmt.Sprintf("could not parse kubeconfig: %v", err),
+			AttributePath: cty.GetAttrPath("kube_config_attributes"),
+		}}
+	}
+	_ = d.Set("kube_config_attributes", flattenKubeconfigAttributes(attributes))
+	return nil
+}
@@ -0,0 +1,115 @@
+package metakube
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"go.uber.org/zap"
+)
+
+func TestMetakubeResourceClusterFetchKubeconfigs(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v2/projects/p1/clusters/c1/kubeconfig":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(testKubeconfig))
+		case "/api/v2/projects/p1/clusters/c1/kubeloginkubeconfig":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte("kubelogin"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	testCases := []struct {
+		mode         string
+		wantRequests []string
+		want         map[string]string
+		wantWarnings []string
+	}{
+		{
+			mode: fetchKubeconfigsNone,
+		},
+		{
+			mode:         fetchKubeconfigsAdmin,
+			wantRequests: []string{"/api/v2/projects/p1/clusters/c1/kubeconfig"},
+			want:         map[string]string{"kube_config": testKubeconfig},
+		},
+		{
+			mode: fetchKubeconfigsAll,
+			wantRequests: []string{
+				"/api/v2/projects/p1/clusters/c1/kubeconfig",
+				"/api/v2/projects/p1/clusters/c1/kubeloginkubeconfig",
+				"/api/v2/projects/p1/clusters/c1/oidckubeconfig",
+			},
+			want: map[string]string{
+				"kube_config":            testKubeconfig,
+				"kube_login_kube_config": "kubelogin",
+			},
+			wantWarnings: []string{"oidc_kube_config"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			requests = nil
+			d := schema.TestResourceDataRaw(t, metakubeResourceCluster().Schema, map[string]interface{}{
+				"fetch_kubeconfigs": tc.mode,
+				"spec": []interface{}{map[string]interface{}{
+					"syseleven_auth": []interface{}{map[string]interface{}{"realm": "r"}},
+				}},
+			})
+			d.SetId("c1")
+
+			diagnostics := metakubeResourceClusterFetchKubeconfigs(context.Background(), d, k, "p1")
+
+			sort.Strings(requests)
+			if diff := cmp.Diff(tc.wantRequests, requests); diff != "" {
+				t.Fatalf("requests mismatch (-want +got):\n%s", diff)
+			}
+			var warnings []string
+			for _, v := range diagnostics {
+				if v.Severity != diag.Warning {
+					t.Fatalf("unexpected error: %v", v.Summary)
+				}
+				warnings = append(warnings, v.AttributePath[0].(cty.GetAttrStep).Name)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, warnings); diff != "" {
+				t.Fatalf("warnings mismatch (-want +got):\n%s", diff)
+			}
+			for _, attr := range []string{"kube_config", "oidc_kube_config", "kube_login_kube_config"} {
+				if got := d.Get(attr).(string); got != tc.want[attr] {
+					t.Fatalf("want %s %q, got %q", attr, tc.want[attr], got)
+				}
+			}
+		})
+	}
+}
+
+func TestMetakubeResourceClusterSetAdminKubeconfigUnchanged(t *testing.T) {
+	k := &metakubeProviderMeta{log: zap.NewNop().Sugar()}
+	d := metakubeResourceCluster().TestResourceData()
+
+	if diagnostics := metakubeResourceClusterSetAdminKubeconfig(context.Background(), d, k, testKubeconfig); len(diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	_ = d.Set("kube_config_attributes", []interface{}{map[string]interface{}{"token": "kept"}})
+
+	metakubeResourceClusterSetAdminKubeconfig(context.Background(), d, k, testKubeconfig)
+	if got := d.Get("kube_config_attributes.0.token"); got != "kept" {
+		t.Fatalf("want unchanged kubeconfig not to be rewritten, got token %v", got)
+	}
+}
@@ -2,7 +2,6 @@ package metakube
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/syseleven/go-metakube/client/project"
 
@@ -12,7 +11,7 @@ import (
 )
 
 func metakubeResourceClusterRoleBinding() *schema.Resource {
-	return &schema.Resource{
+	return metakubeResourceWithProjectName(&schema.Resource{
 		CreateContext: metakubeResourceClusterRoleBindingCreate,
 		ReadContext:   metakubeResourceClusterRoleBindingRead,
 		DeleteContext: metakubeResourceClusterRoleBindingDelete,
@@ -21,13 +20,6 @@ func metakubeResourceClusterRoleBinding() *schema.Resource {
 		},
 
 		Schema: map[string]*schema.Schema{
-			"project_id": {
-				Type:         schema.TypeString,
-				Required:     true,
-				ValidateFunc: validation.NoZeroValues,
-				ForceNew:     true,
-				Description:  "The id of the project resource belongs to",
-			},
 			"cluster_id": {
 				Type:         schema.TypeString,
 				ValidateFunc: validation.NoZeroValues,
@@ -66,23 +58,27 @@ func metakubeResourceClusterRoleBinding() *schema.Resource {
 				},
 			},
 		},
-	}
+	})
 }
 
 func metakubeResourceClusterRoleBindingCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	k := m.(*metakubeProviderMeta)
+	projectID, diagnostics := metakubeResourceProjectID(ctx, d, k)
+	if diagnostics.HasError() {
+		return diagnostics
+	}
 
 	subjects := metakubeClusterRoleBindingExpandSubjects(d.Get("subject"))
 	for _, sub := range subjects {
 		params := project.NewBindUserToClusterRoleV2Params().
 			WithContext(ctx).
-			WithProjectID(d.Get("project_id").(string)).
+			WithProjectID(projectID).
 			WithClusterID(d.Get("cluster_id").(string)).
 			WithRoleID(d.Get("cluster_role_name").(string)).
 			WithBody(&sub)
 		_, err := k.client.Project.BindUserToClusterRoleV2(params, k.auth)
 		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to create cluster role bindings: %s", stringifyResponseError(err)))
+			return diagFromAPIError(err, "failed to create cluster role bindings", nil)
 		}
 	}
 	d.SetId(d.Get("cluster_role_name").(string))
@@ -134,7 +130,7 @@ func metakubeResourceClusterRoleBindingDelete(ctx context.Context, d *schema.Res
 			WithBody(&sub)
 		_, err := k.client.Project.UnbindUserFromClusterRoleBindingV2(params, k.auth)
 		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to delete cluster role binding: %s", stringifyResponseError(err)))
+			return diagFromAPIError(err, "failed to delete cluster role binding", nil)
 		}
 	}
 	return nil
@@ -417,3 +417,102 @@ func metakubeResourceClusterOpenstackCloudSpecApplicationCredentialsFields() map
 		},
 	}
 }
+
+func metakubeResourceClusterHealthFields() map[string]*schema.Schema {
+	status := func(component string) *schema.Schema {
+		return &schema.Schema{
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Status of the " + component + ", one of up, down or provisioning",
+		}
+	}
+	return map[string]*schema.Schema{
+		"apiserver":                       status("API server"),
+		"etcd":                            status("etcd"),
+		"controller":                      status("controller manager"),
+		"scheduler":                       status("scheduler"),
+		"machine_controller":              status("machine controller"),
+		"cloud_provider_infrastructure":   status("cloud provider infrastructure"),
+		"user_cluster_controller_manager": status("user cluster controller manager"),
+		"ready": {
+			Type:        schema.TypeBool,
+			Computed:    true,
+			Description: "Whether all components are up",
+		},
+	}
+}
+
+func metakubeResourceClusterWaitForFields() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"enabled": {
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     true,
+			Description: "Wait for the cluster to be ready on create and update",
+		},
+		"components": {
+			Type:        schema.TypeSet,
+			Optional:    true,
+			Description: "Components that must be up, all components if not set",
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice(clusterHealthComponentNames, false),
+			},
+		},
+		"failure_severity": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "error",
+			ValidateFunc: validation.StringInSlice([]string{"error", "warning"}, false),
+			Description:  "Whether a cluster not getting ready in time is reported as an error or a warning",
+		},
+	}
+}
+
+func metakubeResourceClusterKubeconfigAttributesFields() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"host": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Kubernetes API server address",
+		},
+		"cluster_ca_certificate": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "PEM encoded certificate authority of the API server",
+		},
+		"client_certificate": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "PEM encoded client certificate",
+		},
+		"client_key": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "PEM encoded client key",
+		},
+		"token": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Sensitive:   true,
+			Description: "Bearer token",
+		},
+		"context_name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Name of the kubeconfig context",
+		},
+		"cluster_name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Name of the kubeconfig cluster",
+		},
+		"user_name": {
+			Type:        schema.TypeString,
+			Computed:    true,
+			Description: "Name of the kubeconfig user",
+		},
+	}
+}
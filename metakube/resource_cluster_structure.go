@@ -1,6 +1,10 @@
 package metakube
 
 import (
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/syseleven/go-metakube/models"
 )
 
@@ -290,8 +294,110 @@ func flattenAzureSpec(in *models.AzureCloudSpec) []interface{} {
 	return []interface{}{att}
 }
 
+// health statuses reported by the API
+const (
+	clusterHealthDown         models.HealthStatus = 0
+	clusterHealthUp           models.HealthStatus = 1
+	clusterHealthProvisioning models.HealthStatus = 2
+)
+
+func flattenClusterHealthStatus(in models.HealthStatus) string {
+	switch in {
+	case clusterHealthDown:
+		return "down"
+	case clusterHealthUp:
+		return "up"
+	case clusterHealthProvisioning:
+		return "provisioning"
+	default:
+		return "unknown"
+	}
+}
+
+// names of the cluster components reported by the health endpoint, as used
+// in the health and wait_for attributes
+var clusterHealthComponentNames = []string{
+	"apiserver",
+	"etcd",
+	"controller",
+	"scheduler",
+	"machine_controller",
+	"cloud_provider_infrastructure",
+	"user_cluster_controller_manager",
+}
+
+func metakubeClusterHealthComponents(in *models.ClusterHealth) map[string]models.HealthStatus {
+	return map[string]models.HealthStatus{
+		"apiserver":                       in.Apiserver,
+		"etcd":                            in.Etcd,
+		"controller":                      in.Controller,
+		"scheduler":                       in.Scheduler,
+		"machine_controller":              in.MachineController,
+		"cloud_provider_infrastructure":   in.CloudProviderInfrastructure,
+		"user_cluster_controller_manager": in.UserClusterControllerManager,
+	}
+}
+
+func metakubeResourceClusterFlattenHealth(in *models.ClusterHealth) []interface{} {
+	if in == nil {
+		return []interface{}{}
+	}
+	att := map[string]interface{}{
+		"ready": metakubeClusterHealthReady(in, nil),
+	}
+	for name, status := range metakubeClusterHealthComponents(in) {
+		att[name] = flattenClusterHealthStatus(status)
+	}
+	return []interface{}{att}
+}
+
+// metakubeClusterHealthReady tells whether the given components are up, all
+// components if none are given.
+func metakubeClusterHealthReady(in *models.ClusterHealth, components []string) bool {
+	if len(components) == 0 {
+		components = clusterHealthComponentNames
+	}
+	statuses := metakubeClusterHealthComponents(in)
+	for _, c := range components {
+		if statuses[c] != clusterHealthUp {
+			return false
+		}
+	}
+	return true
+}
+
 // expanders
 
+type clusterWaitFor struct {
+	enabled    bool
+	components []string
+	severity   diag.Severity
+}
+
+func metakubeResourceClusterExpandWaitFor(p []interface{}) clusterWaitFor {
+	ret := clusterWaitFor{
+		enabled:  true,
+		severity: diag.Error,
+	}
+	if len(p) < 1 || p[0] == nil {
+		return ret
+	}
+	in := p[0].(map[string]interface{})
+	if v, ok := in["enabled"]; ok {
+		ret.enabled = v.(bool)
+	}
+	if v, ok := in["components"]; ok {
+		for _, c := range v.(*schema.Set).List() {
+			ret.components = append(ret.components, c.(string))
+		}
+		sort.Strings(ret.components)
+	}
+	if v, ok := in["failure_severity"]; ok && v.(string) == "warning" {
+		ret.severity = diag.Warning
+	}
+	return ret
+}
+
 func metakubeResourceClusterExpandSpec(p []interface{}, dcName string, include func(string) bool) *models.ClusterSpec {
 	if len(p) < 1 {
 		return nil
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/syseleven/go-metakube/models"
 )
 
@@ -769,3 +771,133 @@ func TestExpandAuditLogging(t *testing.T) {
 		t.Fatalf("want %+v, got %+v", want, got)
 	}
 }
+
+func TestMetakubeResourceClusterFlattenHealth(t *testing.T) {
+	cases := []struct {
+		Input          *models.ClusterHealth
+		ExpectedOutput []interface{}
+	}{
+		{
+			&models.ClusterHealth{
+				Apiserver:                    1,
+				CloudProviderInfrastructure:  1,
+				Controller:                   1,
+				Etcd:                         1,
+				MachineController:            2,
+				Scheduler:                    1,
+				UserClusterControllerManager: 0,
+			},
+			[]interface{}{
+				map[string]interface{}{
+					"apiserver":                       "up",
+					"etcd":                            "up",
+					"controller":                      "up",
+					"scheduler":                       "up",
+					"machine_controller":              "provisioning",
+					"cloud_provider_infrastructure":   "up",
+					"user_cluster_controller_manager": "down",
+					"ready":                           false,
+				},
+			},
+		},
+		{
+			&models.ClusterHealth{
+				Apiserver:                    1,
+				CloudProviderInfrastructure:  1,
+				Controller:                   1,
+				Etcd:                         1,
+				MachineController:            1,
+				Scheduler:                    1,
+				UserClusterControllerManager: 1,
+			},
+			[]interface{}{
+				map[string]interface{}{
+					"apiserver":                       "up",
+					"etcd":                            "up",
+					"controller":                      "up",
+					"scheduler":                       "up",
+					"machine_controller":              "up",
+					"cloud_provider_infrastructure":   "up",
+					"user_cluster_controller_manager": "up",
+					"ready":                           true,
+				},
+			},
+		},
+		{
+			nil,
+			[]interface{}{},
+		},
+	}
+
+	for _, tc := range cases {
+		output := metakubeResourceClusterFlattenHealth(tc.Input)
+		if diff := cmp.Diff(tc.ExpectedOutput, output); diff != "" {
+			t.Fatalf("Unexpected output from flattener: mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestMetakubeClusterHealthReady(t *testing.T) {
+	health := &models.ClusterHealth{
+		Apiserver:                    1,
+		CloudProviderInfrastructure:  1,
+		Controller:                   1,
+		Etcd:                         1,
+		MachineController:            2,
+		Scheduler:                    1,
+		UserClusterControllerManager: 1,
+	}
+	cases := []struct {
+		Components []string
+		Expected   bool
+	}{
+		{nil, false},
+		{[]string{"apiserver"}, true},
+		{[]string{"apiserver", "machine_controller"}, false},
+	}
+
+	for _, tc := range cases {
+		if got := metakubeClusterHealthReady(health, tc.Components); got != tc.Expected {
+			t.Fatalf("components %v: want %v, got %v", tc.Components, tc.Expected, got)
+		}
+	}
+}
+
+func TestMetakubeResourceClusterExpandWaitFor(t *testing.T) {
+	cases := []struct {
+		Input          []interface{}
+		ExpectedOutput clusterWaitFor
+	}{
+		{
+			[]interface{}{},
+			clusterWaitFor{enabled: true, severity: diag.Error},
+		},
+		{
+			[]interface{}{
+				map[string]interface{}{
+					"enabled":          true,
+					"components":       schema.NewSet(schema.HashString, []interface{}{"machine_controller", "apiserver"}),
+					"failure_severity": "warning",
+				},
+			},
+			clusterWaitFor{enabled: true, components: []string{"apiserver", "machine_controller"}, severity: diag.Warning},
+		},
+		{
+			[]interface{}{
+				map[string]interface{}{
+					"enabled":          false,
+					"components":       schema.NewSet(schema.HashString, nil),
+					"failure_severity": "error",
+				},
+			},
+			clusterWaitFor{enabled: false, severity: diag.Error},
+		},
+	}
+
+	for _, tc := range cases {
+		output := metakubeResourceClusterExpandWaitFor(tc.Input)
+		if diff := cmp.Diff(tc.ExpectedOutput, output, cmp.AllowUnexported(clusterWaitFor{})); diff != "" {
+			t.Fatalf("Unexpected output from expander: mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
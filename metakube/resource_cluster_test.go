@@ -26,7 +26,7 @@ func testSweepClusters(region string) error {
 	params := project.NewListClustersV2Params().WithProjectID(projectID)
 	records, err := meta.client.Project.ListClustersV2(params, meta.auth)
 	if err != nil {
-		return fmt.Errorf("sweep list clusters: %s", stringifyResponseError(err))
+		return fmt.Errorf("sweep list clusters: %s", newAPIError(err))
 	}
 
 	for _, rec := range records.Payload {
@@ -38,7 +38,7 @@ func testSweepClusters(region string) error {
 			WithProjectID(projectID).
 			WithClusterID(rec.ID)
 		if _, err := meta.client.Project.DeleteClusterV2(p, meta.auth); err != nil {
-			return fmt.Errorf("delete cluster: %v", stringifyResponseError(err))
+			return fmt.Errorf("delete cluster: %v", newAPIError(err))
 		}
 	}
 
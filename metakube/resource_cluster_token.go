@@ -0,0 +1,60 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/syseleven/go-metakube/client/project"
+)
+
+// metakubeResourceClusterTokenRotationDiff plans new kubeconfigs for tokens
+// revoked by a changed rotation trigger.
+func metakubeResourceClusterTokenRotationDiff(_ context.Context, d *schema.ResourceDiff, _ interface{}) error {
+	if d.Id() == "" || !d.HasChange("admin_token_rotation_trigger") || d.Get("fetch_kubeconfigs") == fetchKubeconfigsNone {
+		return nil
+	}
+	if err := d.SetNewComputed("kube_config"); err != nil {
+		return err
+	}
+	return d.SetNewComputed("kube_config_attributes")
+}
+
+// metakubeResourceClusterRotateToken calls revoke when trigger changed. If
+// revoking fails the old trigger is kept in state, so the next apply retries.
+func metakubeResourceClusterRotateToken(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta, projectID, trigger string, revoke func(context.Context, *metakubeProviderMeta, string, string) error) error {
+	if !d.HasChange(trigger) {
+		return nil
+	}
+	if err := revoke(ctx, k, projectID, d.Id()); err != nil {
+		old, _ := d.GetChange(trigger)
+		_ = d.Set(trigger, old)
+		return err
+	}
+	return nil
+}
+
+func metakubeResourceClusterRevokeAdminToken(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) error {
+	p := project.NewRevokeClusterAdminTokenV2Params().
+		WithContext(ctx).
+		WithProjectID(projectID).
+		WithClusterID(clusterID)
+	if _, err := k.client.Project.RevokeClusterAdminTokenV2(p, k.auth); err != nil {
+		return fmt.Errorf("unable to revoke admin token of cluster '%s': %w", clusterID, newAPIError(err))
+	}
+	return nil
+}
+
+// metakubeResourceClusterRevokeViewerToken revokes the token of read-only
+// kubeconfigs. The API has no endpoint for the provider to get a viewer
+// kubeconfig, so there is nothing to refresh.
+func metakubeResourceClusterRevokeViewerToken(ctx context.Context, k *metakubeProviderMeta, projectID, clusterID string) error {
+	p := project.NewRevokeClusterViewerTokenV2Params().
+		WithContext(ctx).
+		WithProjectID(projectID).
+		WithClusterID(clusterID)
+	if _, err := k.client.Project.RevokeClusterViewerTokenV2(p, k.auth); err != nil {
+		return fmt.Errorf("unable to revoke viewer token of cluster '%s': %w", clusterID, newAPIError(err))
+	}
+	return nil
+}
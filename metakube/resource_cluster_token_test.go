@@ -0,0 +1,160 @@
+package metakube
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestMetakubeResourceClusterTokenRotationDiff(t *testing.T) {
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"admin_token_rotation_trigger": {Type: schema.TypeString, Optional: true},
+			"kube_config":                  {Type: schema.TypeString, Computed: true},
+			"kube_config_attributes": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Resource{Schema: metakubeResourceClusterKubeconfigAttributesFields()},
+			},
+		},
+		CustomizeDiff: metakubeResourceClusterTokenRotationDiff,
+	}
+	state := &terraform.InstanceState{ID: "c1", Attributes: map[string]string{
+		"admin_token_rotation_trigger": "2024-01",
+		"kube_config":                  "old",
+	}}
+
+	for _, tc := range []struct {
+		trigger string
+		want    bool
+	}{
+		{"2024-01", false},
+		{"2024-02", true},
+	} {
+		d, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"admin_token_rotation_trigger": tc.trigger}), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := d != nil && d.Attributes["kube_config"] != nil && d.Attributes["kube_config"].NewComputed
+		if got != tc.want {
+			t.Fatalf("trigger %s: want new kube_config %v, got %v", tc.trigger, tc.want, got)
+		}
+	}
+}
+
+func TestMetakubeResourceClusterRevokeAdminToken(t *testing.T) {
+	revoked := false
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v2/projects/p1/clusters/c1/token":
+			revoked = true
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/projects/p1/clusters/c1/kubeconfig":
+			token := "admin-token"
+			if revoked {
+				token = "new-token"
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			_, _ = w.Write([]byte(strings.Replace(testKubeconfig, "token: admin-token", "token: "+token, 1)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	ctx := context.Background()
+
+	if err := metakubeResourceClusterRevokeAdminToken(ctx, k, "p1", "c1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d := metakubeResourceCluster().TestResourceData()
+	d.SetId("c1")
+	// states written before fetch_kubeconfigs existed fetch all kubeconfigs
+	if diagnostics := metakubeResourceClusterFetchKubeconfigs(ctx, d, k, "p1"); len(diagnostics) > 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+	if got := d.Get("kube_config_attributes.0.token"); got != "new-token" {
+		t.Fatalf("want kubeconfig with new token, got %v", got)
+	}
+}
+
+func TestMetakubeResourceClusterRotateAdminTokenError(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"admin_token_rotation_trigger": {Type: schema.TypeString, Optional: true},
+		},
+	}
+	state := &terraform.InstanceState{ID: "c1", Attributes: map[string]string{
+		"admin_token_rotation_trigger": "2024-01",
+	}}
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"admin_token_rotation_trigger": "2024-02"}), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state, diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := metakubeResourceClusterRotateToken(context.Background(), d, k, "p1", "admin_token_rotation_trigger", metakubeResourceClusterRevokeAdminToken); err == nil {
+		t.Fatal("want error")
+	}
+	if got := d.State().Attributes["admin_token_rotation_trigger"]; got != "2024-01" {
+		t.Fatalf("want old trigger in state, got %q", got)
+	}
+}
+
+func TestMetakubeResourceClusterRevokeViewerToken(t *testing.T) {
+	revoked := false
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/api/v2/projects/p1/clusters/c1/viewertoken" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		revoked = true
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+
+	if err := metakubeResourceClusterRevokeViewerToken(context.Background(), k, "p1", "c1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !revoked {
+		t.Fatal("viewer token was not revoked")
+	}
+}
+
+func TestMetakubeResourceClusterRotateViewerTokenError(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"viewer_token_rotation_trigger": {Type: schema.TypeString, Optional: true},
+		},
+	}
+	state := &terraform.InstanceState{ID: "c1", Attributes: map[string]string{
+		"viewer_token_rotation_trigger": "2024-01",
+	}}
+	diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigRaw(map[string]interface{}{"viewer_token_rotation_trigger": "2024-02"}), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d, err := schema.InternalMap(r.Schema).Data(state, diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := metakubeResourceClusterRotateToken(context.Background(), d, k, "p1", "viewer_token_rotation_trigger", metakubeResourceClusterRevokeViewerToken); err == nil {
+		t.Fatal("want error")
+	}
+	if got := d.State().Attributes["viewer_token_rotation_trigger"]; got != "2024-01" {
+		t.Fatalf("want old trigger in state, got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package metakube
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -52,7 +53,7 @@ func (data *metakubeResourceClusterOpenstackValidationData) setParams(ctx contex
 	p.SetContext(ctx)
 }
 
-func newOpenstackValidationData(d *schema.ResourceData) metakubeResourceClusterOpenstackValidationData {
+func newOpenstackValidationData(d *schema.ResourceDiff) metakubeResourceClusterOpenstackValidationData {
 	return metakubeResourceClusterOpenstackValidationData{
 		dcName:                       toStrPtrOrNil(d.Get("dc_name")),
 		domain:                       strToPtr("Default"),
@@ -74,13 +75,89 @@ func toStrPtrOrNil(v interface{}) *string {
 	return strToPtr(v.(string))
 }
 
-func metakubeResourceClusterValidateClusterFields(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) diag.Diagnostics {
+// metakubeResourceClusterValidateDiff checks the planned cluster against the
+// API, so that invalid values are reported by plan rather than half way
+// through apply. Values not known until apply are not checked.
+func metakubeResourceClusterValidateDiff(ctx context.Context, d *schema.ResourceDiff, m interface{}) error {
+	k, ok := m.(*metakubeProviderMeta)
+	if !ok {
+		return nil
+	}
+	ret := metakubeResourceClusterValidateClusterFields(ctx, d, k)
+	if d.Id() != "" && d.HasChange("spec.0.version") && d.NewValueKnown("spec.0.version") {
+		o, n := d.GetChange("spec.0.version")
+		if !metakubeResourceClusterIsVersionDowngraded(ctx, o, n, m) {
+			ret = append(ret, metakubeResourceClusterValidateVersionUpgrade(ctx, d.Get("project_id").(string), d.Id(), o.(string), n.(string), k)...)
+		}
+	}
+	ret = append(ret, metakubeResourceClusterValidateSSHAgent(d)...)
+	return diagnosticsError(ret)
+}
+
+// diagnosticsError returns the errors of the diagnostics as one error, which
+// is how CustomizeDiff reports them. It is shown at the attribute of the first
+// error, the messages of the other errors name their attribute.
+func diagnosticsError(diagnostics diag.Diagnostics) error {
+	var (
+		path cty.Path
+		errs []error
+	)
+	for _, d := range diagnostics {
+		if d.Severity != diag.Error {
+			continue
+		}
+		msg := d.Summary
+		if d.Detail != "" {
+			msg = fmt.Sprintf("%s: %s", d.Summary, d.Detail)
+		}
+		if len(errs) == 0 {
+			path = d.AttributePath
+		} else if len(d.AttributePath) > 0 {
+			msg = fmt.Sprintf("%s: %s", attributePathString(d.AttributePath), msg)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	err := errors.Join(errs...)
+	if len(path) == 0 {
+		return err
+	}
+	return path.NewError(err)
+}
+
+// attributePathString returns the path as a key like spec.0.version.
+func attributePathString(path cty.Path) string {
+	var parts []string
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			parts = append(parts, s.Name)
+		case cty.IndexStep:
+			if s.Key.Type() == cty.String {
+				parts = append(parts, s.Key.AsString())
+			} else if s.Key.Type() == cty.Number {
+				parts = append(parts, s.Key.AsBigFloat().String())
+			}
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func metakubeResourceClusterValidateClusterFields(ctx context.Context, d *schema.ResourceDiff, k *metakubeProviderMeta) diag.Diagnostics {
 	ret := metakubeResourceValidateVersionExistence(ctx, d, k)
 	if _, ok := d.GetOk("spec.0.cloud.0.openstack.0"); !ok {
 		return ret
 	}
+	if d.Id() != "" && !d.HasChanges("dc_name", "spec.0.cloud") {
+		return ret
+	}
+	if !metakubeResourceClusterOpenstackValuesKnown(d) {
+		return ret
+	}
 	data := newOpenstackValidationData(d)
-	hasAuthData := (data.username == nil || *data.username != "") && (data.applicationCredentialsID == nil || *data.applicationCredentialsSecret != "")
+	hasAuthData := data.username != nil && *data.username != "" || data.applicationCredentialsSecret != nil && *data.applicationCredentialsSecret != ""
 	if hasAuthData {
 		ret = append(ret, metakubeResourceClusterValidateFloatingIPPool(ctx, d, k)...)
 		ret = append(ret, metakubeResourceClusterValidateOpenstackNetwork(ctx, d, k)...)
@@ -89,14 +166,50 @@ func metakubeResourceClusterValidateClusterFields(ctx context.Context, d *schema
 	return append(ret, metakubeResourceClusterValidateAccessCredentialsSet(d)...)
 }
 
-func metakubeResourceClusterValidateVersionUpgrade(ctx context.Context, projectID, newVersion string, cluster *models.Cluster, k *metakubeProviderMeta) diag.Diagnostics {
+// metakubeResourceClusterOpenstackValuesKnown tells whether the values used to
+// access OpenStack are known at plan time.
+func metakubeResourceClusterOpenstackValuesKnown(d *schema.ResourceDiff) bool {
+	for _, key := range []string{
+		"dc_name",
+		"spec.0.cloud.0.openstack.0.user_credentials.0.username",
+		"spec.0.cloud.0.openstack.0.user_credentials.0.password",
+		"spec.0.cloud.0.openstack.0.user_credentials.0.project_id",
+		"spec.0.cloud.0.openstack.0.user_credentials.0.project_name",
+		"spec.0.cloud.0.openstack.0.application_credentials.0.id",
+		"spec.0.cloud.0.openstack.0.application_credentials.0.secret",
+	} {
+		if !d.NewValueKnown(key) {
+			return false
+		}
+	}
+	return true
+}
+
+func metakubeResourceClusterValidateSSHAgent(d *schema.ResourceDiff) diag.Diagnostics {
+	if d.Id() != "" && !d.HasChanges("sshkeys", "spec.0.enable_ssh_agent") {
+		return nil
+	}
+	if !d.NewValueKnown("sshkeys") || !d.NewValueKnown("spec.0.enable_ssh_agent") {
+		return nil
+	}
+	if d.Get("sshkeys").(*schema.Set).Len() == 0 || d.Get("spec.0.enable_ssh_agent").(bool) {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity:      diag.Error,
+		AttributePath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("enable_ssh_agent"),
+		Summary:       "SSH Agent must be enabled in order to automatically manage ssh keys",
+	}}
+}
+
+func metakubeResourceClusterValidateVersionUpgrade(ctx context.Context, projectID, clusterID, oldVersion, newVersion string, k *metakubeProviderMeta) diag.Diagnostics {
 	p := project.NewGetClusterUpgradesV2Params().
 		WithContext(ctx).
 		WithProjectID(projectID).
-		WithClusterID(cluster.ID)
+		WithClusterID(clusterID)
 	r, err := k.client.Project.GetClusterUpgradesV2(p, k.auth)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromAPIError(err, fmt.Sprintf("unable to get upgrades of cluster '%s'", clusterID), cty.GetAttrPath("spec").IndexInt(0).GetAttr("version"))
 	}
 	var available []string
 	for _, item := range r.Payload {
@@ -107,21 +220,21 @@ func metakubeResourceClusterValidateVersionUpgrade(ctx context.Context, projectI
 	}
 	return diag.Diagnostics{{
 		Severity:      diag.Error,
-		Summary:       fmt.Sprintf("not allowed upgrade %s->%s", cluster.Spec.Version, newVersion),
+		Summary:       fmt.Sprintf("not allowed upgrade %s->%s", oldVersion, newVersion),
 		AttributePath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("version"),
 		Detail:        fmt.Sprintf("Please select one of available upgrades: %v", available),
 	}}
 }
 
-func metakubeResourceValidateVersionExistence(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) diag.Diagnostics {
-	if !d.HasChange("spec.0.version") && d.Id() != "" {
+func metakubeResourceValidateVersionExistence(ctx context.Context, d *schema.ResourceDiff, k *metakubeProviderMeta) diag.Diagnostics {
+	if !d.HasChange("spec.0.version") && d.Id() != "" || !d.NewValueKnown("spec.0.version") {
 		return nil
 	}
 	version := d.Get("spec.0.version").(string)
 	p := versions.NewGetMasterVersionsParams().WithContext(ctx)
 	r, err := k.client.Versions.GetMasterVersions(p, k.auth)
 	if err != nil {
-		return diag.Errorf("%s", stringifyResponseError(err))
+		return diagFromAPIError(err, "Can't list versions", nil)
 	}
 
 	available := make([]string, 0)
@@ -140,7 +253,7 @@ func metakubeResourceValidateVersionExistence(ctx context.Context, d *schema.Res
 	}}
 }
 
-func metakubeResourceClusterValidateFloatingIPPool(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) diag.Diagnostics {
+func metakubeResourceClusterValidateFloatingIPPool(ctx context.Context, d *schema.ResourceDiff, k *metakubeProviderMeta) diag.Diagnostics {
 	nets, err := validateOpenstackNetworkExistsIfSet(ctx, d, k, "spec.0.cloud.0.openstack.0.floating_ip_pool", true)
 	if err != nil {
 		var diagnoseDetail string
@@ -163,7 +276,7 @@ func metakubeResourceClusterValidateFloatingIPPool(ctx context.Context, d *schem
 	return nil
 }
 
-func metakubeResourceClusterValidateOpenstackNetwork(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) diag.Diagnostics {
+func metakubeResourceClusterValidateOpenstackNetwork(ctx context.Context, d *schema.ResourceDiff, k *metakubeProviderMeta) diag.Diagnostics {
 	allnets, err := validateOpenstackNetworkExistsIfSet(ctx, d, k, "spec.0.cloud.0.openstack.0.network", false)
 	if err != nil {
 		names := make([]string, 0)
@@ -186,9 +299,9 @@ func metakubeResourceClusterValidateOpenstackNetwork(ctx context.Context, d *sch
 	return nil
 }
 
-func validateOpenstackNetworkExistsIfSet(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta, field string, external bool) ([]*models.OpenstackNetwork, error) {
+func validateOpenstackNetworkExistsIfSet(ctx context.Context, d *schema.ResourceDiff, k *metakubeProviderMeta, field string, external bool) ([]*models.OpenstackNetwork, error) {
 	value, ok := d.GetOk(field)
-	if !ok {
+	if !ok || !d.NewValueKnown(field) {
 		return nil, nil
 	}
 
@@ -197,9 +310,12 @@ func validateOpenstackNetworkExistsIfSet(ctx context.Context, d *schema.Resource
 	return all, err
 }
 
-func diagnoseOpenstackSubnetWithIDExistsIfSet(ctx context.Context, d *schema.ResourceData, k *metakubeProviderMeta) diag.Diagnostics {
+func diagnoseOpenstackSubnetWithIDExistsIfSet(ctx context.Context, d *schema.ResourceDiff, k *metakubeProviderMeta) diag.Diagnostics {
+	if !d.NewValueKnown("spec.0.cloud.0.openstack.0.network") || !d.NewValueKnown("spec.0.cloud.0.openstack.0.subnet_id") {
+		return nil
+	}
 	data := newOpenstackValidationData(d)
-	if data.network == nil || data.subnetID == nil {
+	if data.network == nil || data.subnetID == nil || *data.subnetID == "" {
 		return nil
 	}
 	network, _, err := getNetwork(ctx, k, data, *data.network, true)
@@ -222,7 +338,7 @@ func diagnoseOpenstackSubnetWithIDExistsIfSet(ctx context.Context, d *schema.Res
 	return diag.Diagnostics{{
 		Severity:      diag.Error,
 		Summary:       fmt.Sprintf("invalid value: %v", err),
-		AttributePath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("cloud").IndexInt(0).GetAttr("openstack").IndexInt(0).GetAttr("subnetID"),
+		AttributePath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("cloud").IndexInt(0).GetAttr("openstack").IndexInt(0).GetAttr("subnet_id"),
 		Detail:        diagnoseDetail,
 	}}
 }
@@ -232,7 +348,7 @@ func getNetwork(ctx context.Context, k *metakubeProviderMeta, data metakubeResou
 	data.setParams(ctx, p)
 	res, err := k.client.Openstack.ListOpenstackNetworks(p, k.auth)
 	if err != nil {
-		return nil, nil, fmt.Errorf("find network instance %v", stringifyResponseError(err))
+		return nil, nil, fmt.Errorf("find network instance %w", newAPIError(err))
 	}
 	ret := findNetwork(res.Payload, name, external)
 	if ret == nil {
@@ -256,7 +372,7 @@ func getSubnet(ctx context.Context, k *metakubeProviderMeta, data metakubeResour
 	p.SetNetworkID(&networkID)
 	res, err := k.client.Openstack.ListOpenstackSubnets(p, k.auth)
 	if err != nil {
-		return nil, false, fmt.Errorf("list network subnets: %v", stringifyResponseError(err))
+		return nil, false, fmt.Errorf("list network subnets: %w", newAPIError(err))
 	}
 	return res.Payload, findSubnet(res.Payload, *data.subnetID) != nil, nil
 }
@@ -270,7 +386,7 @@ func findSubnet(list []*models.OpenstackSubnet, id string) *models.OpenstackSubn
 	return nil
 }
 
-func metakubeResourceClusterValidateAccessCredentialsSet(d *schema.ResourceData) diag.Diagnostics {
+func metakubeResourceClusterValidateAccessCredentialsSet(d *schema.ResourceDiff) diag.Diagnostics {
 	data := newOpenstackValidationData(d)
 
 	username := data.username != nil && *data.username != ""
@@ -0,0 +1,161 @@
+package metakube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestMetakubeResourceClusterValidateDiff(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/upgrades/cluster":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"version": "1.28.5"}, {"version": "1.29.1"}, {"version": "1.29.2"}})
+		case "/api/v2/projects/p1/clusters/c1/upgrades":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"version": "1.29.1"}})
+		case "/api/v1/providers/openstack/networks":
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": "n1", "name": "ext-net", "external": true},
+				{"id": "n2", "name": "private", "external": false},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	openstack := func(network string) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"openstack": []interface{}{map[string]interface{}{
+				"floating_ip_pool": "ext-net",
+				"network":          network,
+				"application_credentials": []interface{}{map[string]interface{}{
+					"id":     "id",
+					"secret": "secret",
+				}},
+			}},
+		}}
+	}
+	testCases := []struct {
+		name     string
+		state    map[string]string
+		version  string
+		cloud    []interface{}
+		sshkeys  []interface{}
+		sshAgent bool
+		wantPath cty.Path
+		wantErr  string
+		// errors reported along with the first one
+		wantMoreErrs []string
+	}{
+		{
+			name:     "valid",
+			version:  "1.28.5",
+			cloud:    openstack("private"),
+			sshkeys:  []interface{}{"key"},
+			sshAgent: true,
+		},
+		{
+			name:     "unknown version",
+			version:  "1.20.0",
+			cloud:    openstack("private"),
+			sshAgent: true,
+			wantPath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("version"),
+			wantErr:  "unknown version 1.20.0",
+		},
+		{
+			name:     "unknown network",
+			version:  "1.28.5",
+			cloud:    openstack("missing"),
+			sshAgent: true,
+			wantPath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("cloud").IndexInt(0).GetAttr("openstack").IndexInt(0).GetAttr("network"),
+			wantErr:  "network `missing` not found",
+		},
+		{
+			name:     "ssh agent disabled",
+			version:  "1.28.5",
+			cloud:    openstack("private"),
+			sshkeys:  []interface{}{"key"},
+			wantPath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("enable_ssh_agent"),
+			wantErr:  "SSH Agent must be enabled",
+		},
+		{
+			name:     "several errors",
+			version:  "1.20.0",
+			cloud:    openstack("private"),
+			sshkeys:  []interface{}{"key"},
+			wantPath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("version"),
+			wantErr:  "unknown version 1.20.0",
+			wantMoreErrs: []string{
+				"spec.0.enable_ssh_agent: SSH Agent must be enabled",
+			},
+		},
+		{
+			name: "not allowed upgrade",
+			state: map[string]string{
+				"project_id":              "p1",
+				"dc_name":                 "dc",
+				"name":                    "test",
+				"spec.#":                  "1",
+				"spec.0.version":          "1.28.5",
+				"spec.0.enable_ssh_agent": "true",
+			},
+			version:  "1.29.2",
+			sshAgent: true,
+			wantPath: cty.GetAttrPath("spec").IndexInt(0).GetAttr("version"),
+			wantErr:  "not allowed upgrade 1.28.5->1.29.2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var state *terraform.InstanceState
+			if tc.state != nil {
+				state = &terraform.InstanceState{ID: "c1", Attributes: tc.state}
+			}
+			spec := map[string]interface{}{
+				"version":          tc.version,
+				"enable_ssh_agent": tc.sshAgent,
+			}
+			if tc.cloud != nil {
+				spec["cloud"] = tc.cloud
+			}
+			config := map[string]interface{}{
+				"project_id": "p1",
+				"dc_name":    "dc",
+				"name":       "test",
+				"spec":       []interface{}{spec},
+			}
+			if tc.sshkeys != nil {
+				config["sshkeys"] = tc.sshkeys
+			}
+			_, err := metakubeResourceCluster().Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), k)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var pathErr cty.PathError
+			if !errors.As(err, &pathErr) {
+				t.Fatalf("want error with attribute path, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("want error containing %q, got %q", tc.wantErr, err)
+			}
+			for _, want := range tc.wantMoreErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Fatalf("want error containing %q, got %q", want, err)
+				}
+			}
+			if !pathErr.Path.Equals(tc.wantPath) {
+				t.Fatalf("want error at %#v, got %#v", tc.wantPath, pathErr.Path)
+			}
+		})
+	}
+}
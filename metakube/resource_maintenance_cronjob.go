@@ -3,10 +3,9 @@ package metakube
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"strings"
 	"time"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -90,7 +89,7 @@ func metakubeResourceMaintenanceCronJobCreate(ctx context.Context, d *schema.Res
 			return diag.FromErr(err)
 		}
 		if projectID == "" {
-			k.log.Info("owner project for cluster '%s' is not found", clusterID)
+			k.logger(ctx).Info("owner project for cluster '%s' is not found", clusterID)
 			return diag.Errorf("could not find owner project for cluster with id '%s'", clusterID)
 		}
 	}
@@ -101,8 +100,8 @@ func metakubeResourceMaintenanceCronJobCreate(ctx context.Context, d *schema.Res
 	}
 
 	// TODO not sure to remove this
-	if err := metakubeResourceClusterWaitForReady(ctx, k, d.Timeout(schema.TimeoutCreate), projectID, clusterID); err != nil {
-		return diag.Errorf("cluster is not ready: %v", err)
+	if err := metakubeResourceClusterWaitForReady(ctx, k, d.Timeout(schema.TimeoutCreate), projectID, clusterID, nil); err != nil {
+		return notReadyDiagnostics(err, diag.Error, "cluster is not ready")
 	}
 
 	p := project.NewCreateMaintenanceCronJobParams().
@@ -112,20 +111,16 @@ func metakubeResourceMaintenanceCronJobCreate(ctx context.Context, d *schema.Res
 		WithBody(maintenanceCronJob)
 
 	var id models.UID
-	err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutCreate), func() *retry.RetryError {
+	err := retryContext(ctx, d.Timeout(schema.TimeoutCreate), "create maintenance cron job", func(ctx context.Context) *retry.RetryError {
 		r, err := k.client.Project.CreateMaintenanceCronJob(p, k.auth)
 		if err != nil {
-			e := stringifyResponseError(err)
-			if strings.Contains(e, "failed calling webhook") || strings.Contains(e, "Cluster components are not ready yet") {
-				return retry.RetryableError(fmt.Errorf(e))
-			}
-			return retry.NonRetryableError(fmt.Errorf(e))
+			return newAPIError(err).retryError()
 		}
 		id = models.UID(r.Payload.Name)
 		return nil
 	})
 	if err != nil {
-		return diag.Errorf("create a maintenance cron job: %v", err)
+		return diagFromAPIError(err, "create a maintenance cron job", cty.GetAttrPath("spec"))
 	}
 	d.SetId(string(id))
 	d.Set("project_id", projectID)
@@ -154,7 +149,7 @@ func metakubeResourceMaintenanceCronJobUpdate(ctx context.Context, d *schema.Res
 	p.SetPatch(maintenanceCronJob)
 	_, err := k.client.Project.PatchMaintenanceCronJob(p, k.auth)
 	if err != nil {
-		return diag.Errorf("unable to update a maintenance cron job: %v", stringifyResponseError(err))
+		return diagFromAPIError(err, "unable to update a maintenance cron job", cty.GetAttrPath("spec"))
 	}
 
 	if err := metakubeResourceMaintenanceCronJobWaitForReady(ctx, k, d.Timeout(schema.TimeoutUpdate), projectID, clusterID, d.Id()); err != nil {
@@ -176,17 +171,17 @@ func metakubeResourceMaintenanceCronJobRead(ctx context.Context, d *schema.Resou
 
 	r, err := k.client.Project.GetMaintenanceCronJob(p, k.auth)
 	if err != nil {
-		if e, ok := err.(*project.GetMaintenanceCronJobDefault); ok && e.Code() == http.StatusNotFound {
-			k.log.Infof("removing maintenance cron job '%s' from terraform state file, could not find the resource", d.Id())
+		if apiErrorIs(err, apiErrorNotFound) {
+			k.logger(ctx).Infof("removing maintenance cron job '%s' from terraform state file, could not find the resource", d.Id())
 			d.SetId("")
 			return nil
 		}
-		if _, ok := err.(*project.GetMaintenanceCronJobForbidden); ok {
-			k.log.Infof("removing maintenance cron job '%s' from terraform state file, access forbidden", d.Id())
+		if apiErrorIs(err, apiErrorForbidden) {
+			k.logger(ctx).Infof("removing maintenance cron job '%s' from terraform state file, access forbidden", d.Id())
 			d.SetId("")
 			return nil
 		}
-		return diag.Errorf("unable to get maintenance cron job '%s/%s/%s': %s", projectID, clusterID, d.Id(), stringifyResponseError(err))
+		return diagFromAPIError(err, fmt.Sprintf("unable to get maintenance cron job '%s/%s/%s'", projectID, clusterID, d.Id()), nil)
 	}
 
 	_ = d.Set("name", r.Payload.Name)
@@ -205,21 +200,22 @@ func metakubeResourceMaintenanceCronJobDelete(ctx context.Context, d *schema.Res
 	projectID := d.Get("project_id").(string)
 	clusterID := d.Get("cluster_id").(string)
 	p := project.NewDeleteMaintenanceCronJobParams().
+		WithContext(ctx).
 		WithProjectID(projectID).
 		WithClusterID(clusterID).
 		WithMaintenanceCronJobID(d.Id())
 
 	_, err := k.client.Project.DeleteMaintenanceCronJob(p, k.auth)
 	if err != nil {
-		if e, ok := err.(*project.DeleteMaintenanceCronJobDefault); ok && e.Code() == http.StatusNotFound {
-			k.log.Infof("removing maintenance cron job '%s' from terraform state file, could not find the resource", d.Id())
+		if apiErrorIs(err, apiErrorNotFound) {
+			k.logger(ctx).Infof("removing maintenance cron job '%s' from terraform state file, could not find the resource", d.Id())
 			d.SetId("")
 			return nil
 		}
-		return diag.Errorf("unable to delete maintenance cron job '%s': %s", d.Id(), stringifyResponseError(err))
+		return diagFromAPIError(err, fmt.Sprintf("unable to delete maintenance cron job '%s'", d.Id()), nil)
 	}
 
-	err = retry.RetryContext(ctx, d.Timeout(schema.TimeoutDelete), func() *retry.RetryError {
+	err = retryContext(ctx, d.Timeout(schema.TimeoutDelete), "wait for maintenance cron job deletion", func(ctx context.Context) *retry.RetryError {
 		p := project.NewGetMaintenanceCronJobParams().
 			WithContext(ctx).
 			WithProjectID(projectID).
@@ -228,15 +224,15 @@ func metakubeResourceMaintenanceCronJobDelete(ctx context.Context, d *schema.Res
 
 		r, err := k.client.Project.GetMaintenanceCronJob(p, k.auth)
 		if err != nil {
-			if e, ok := err.(*project.GetMaintenanceCronJobDefault); ok && e.Code() == http.StatusNotFound {
-				k.log.Debugf("maintenance cron job '%s' has been destroyed, returned http code: %d", d.Id(), e.Code())
+			if e := newAPIError(err); e.kind == apiErrorNotFound {
+				k.logger(ctx).Debugf("maintenance cron job '%s' has been destroyed, returned http code: %d", d.Id(), e.status)
 				d.SetId("")
 				return nil
 			}
-			return retry.NonRetryableError(fmt.Errorf("unable to get maintenance cron job '%s': %s", d.Id(), stringifyResponseError(err)))
+			return retry.NonRetryableError(fmt.Errorf("unable to get maintenance cron job '%s': %w", d.Id(), newAPIError(err)))
 		}
 
-		k.log.Debugf("maintenance cron job '%s' deletion in progress, deletionTimestamp: %s",
+		k.logger(ctx).Debugf("maintenance cron job '%s' deletion in progress, deletionTimestamp: %s",
 			d.Id(), r.Payload.DeletionTimestamp)
 		return retry.RetryableError(fmt.Errorf("maintenance cron job '%s' deletion in progress", d.Id()))
 	})
@@ -247,7 +243,7 @@ func metakubeResourceMaintenanceCronJobDelete(ctx context.Context, d *schema.Res
 }
 
 func metakubeResourceMaintenanceCronJobWaitForReady(ctx context.Context, k *metakubeProviderMeta, timeout time.Duration, projectID, clusterID, id string) error {
-	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+	return retryContext(ctx, timeout, "wait for maintenance cron job", func(ctx context.Context) *retry.RetryError {
 		p := project.NewGetMaintenanceCronJobParams().
 			WithContext(ctx).
 			WithProjectID(projectID).
@@ -256,7 +252,7 @@ func metakubeResourceMaintenanceCronJobWaitForReady(ctx context.Context, k *meta
 
 		r, err := k.client.Project.GetMaintenanceCronJob(p, k.auth)
 		if err != nil {
-			return retry.RetryableError(fmt.Errorf("unable to get maintenance cron job %s", stringifyResponseError(err)))
+			return retry.RetryableError(fmt.Errorf("unable to get maintenance cron job %w", newAPIError(err)))
 		}
 
 		if r.Payload.Name == "" || r.Payload.Spec.MaintenanceJobTemplate == nil || r.Payload.Spec.MaintenanceJobTemplate.Type == "" {
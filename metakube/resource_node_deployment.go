@@ -3,10 +3,10 @@ package metakube
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
@@ -29,6 +29,7 @@ func metakubeResourceNodeDeployment() *schema.Resource {
 		CustomizeDiff: customdiff.All(
 			validateNodeSpecMatchesCluster(),
 			validateAutoscalerFields(),
+			labelsAllDiff("spec.0.template.0.labels"),
 		),
 
 		Timeouts: &schema.ResourceTimeout{
@@ -71,6 +72,15 @@ func metakubeResourceNodeDeployment() *schema.Resource {
 				},
 			},
 
+			"labels_all": {
+				Type:        schema.TypeMap,
+				Computed:    true,
+				Description: "All labels applied to the nodes, including the provider default labels",
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+
 			"creation_timestamp": {
 				Type:        schema.TypeString,
 				Computed:    true,
@@ -110,26 +120,26 @@ func metakubeResourceNodeDeploymentCreate(ctx context.Context, d *schema.Resourc
 			return diag.FromErr(err)
 		}
 		if projectID == "" {
-			k.log.Info("owner project for cluster '%s' is not found", clusterID)
+			k.logger(ctx).Info("owner project for cluster '%s' is not found", clusterID)
 			return diag.Errorf("could not find owner project for cluster with id '%s'", clusterID)
 		}
 	}
 
 	nodeDeployment := &models.NodeDeployment{
 		Name: d.Get("name").(string),
-		Spec: metakubeNodeDeploymentExpandSpec(d.Get("spec").([]interface{}), true),
+		Spec: metakubeNodeDeploymentExpandSpec(d.Get("spec").([]interface{}), true, k.defaultLabels),
 	}
 
 	if err := metakubeResourceNodeDeploymentVersionCompatibleWithCluster(ctx, k, projectID, clusterID, nodeDeployment); err != nil {
 		return diag.FromErr(err)
 	}
 
-	if err := metakubeResourceClusterWaitForReady(ctx, k, d.Timeout(schema.TimeoutCreate), projectID, clusterID); err != nil {
-		return diag.Errorf("cluster is not ready: %v", err)
+	if err := metakubeResourceClusterWaitForReady(ctx, k, d.Timeout(schema.TimeoutCreate), projectID, clusterID, nil); err != nil {
+		return notReadyDiagnostics(err, diag.Error, "cluster is not ready")
 	}
 
 	// Some cloud providers, like AWS, take some time to finish initializing.
-	err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutCreate), func() *retry.RetryError {
+	err := retryContext(ctx, d.Timeout(schema.TimeoutCreate), "wait for node deployments API", func(ctx context.Context) *retry.RetryError {
 		p := project.NewListMachineDeploymentsParams().
 			WithContext(ctx).
 			WithProjectID(projectID).
@@ -137,8 +147,8 @@ func metakubeResourceNodeDeploymentCreate(ctx context.Context, d *schema.Resourc
 
 		_, err := k.client.Project.ListMachineDeployments(p, k.auth)
 		if err != nil {
-			if e, ok := err.(*project.ListMachineDeploymentsDefault); ok && e.Code() != http.StatusOK {
-				return retry.RetryableError(fmt.Errorf("unable to list node deployments %v", err))
+			if e := newAPIError(err); e.status != 0 {
+				return retry.RetryableError(fmt.Errorf("unable to list node deployments %w", e))
 			}
 			return retry.NonRetryableError(err)
 		}
@@ -156,26 +166,22 @@ func metakubeResourceNodeDeploymentCreate(ctx context.Context, d *schema.Resourc
 		WithBody(nodeDeployment)
 
 	var id string
-	err = retry.RetryContext(ctx, d.Timeout(schema.TimeoutCreate), func() *retry.RetryError {
+	err = retryContext(ctx, d.Timeout(schema.TimeoutCreate), "create node deployment", func(ctx context.Context) *retry.RetryError {
 		r, err := k.client.Project.CreateMachineDeployment(p, k.auth)
 		if err != nil {
-			e := stringifyResponseError(err)
-			if strings.Contains(e, "failed calling webhook") || strings.Contains(e, "Cluster components are not ready yet") {
-				return retry.RetryableError(fmt.Errorf(e))
-			}
-			return retry.NonRetryableError(fmt.Errorf(e))
+			return newAPIError(err).retryError()
 		}
 		id = r.Payload.ID
 		return nil
 	})
 	if err != nil {
-		return diag.Errorf("create a node deployment: %v", err)
+		return diagFromAPIError(err, "create a node deployment", cty.GetAttrPath("spec"))
 	}
 	d.SetId(id)
 	d.Set("project_id", projectID)
 
 	if err := metakubeResourceNodeDeploymentWaitForReady(ctx, k, d.Timeout(schema.TimeoutCreate), projectID, clusterID, id); err != nil {
-		return diag.FromErr(err)
+		return notReadyDiagnostics(err, diag.Error, fmt.Sprintf("node deployment '%s' is not ready", id))
 	}
 
 	return metakubeResourceNodeDeploymentRead(ctx, d, m)
@@ -194,21 +200,28 @@ func metakubeResourceNodeDeploymentRead(ctx context.Context, d *schema.ResourceD
 
 	r, err := k.client.Project.GetMachineDeployment(p, k.auth)
 	if err != nil {
-		if e, ok := err.(*project.GetMachineDeploymentDefault); ok && e.Code() == http.StatusNotFound {
-			k.log.Infof("removing node deployment '%s' from terraform state file, could not find the resource", d.Id())
+		if apiErrorIs(err, apiErrorNotFound) {
+			k.logger(ctx).Infof("removing node deployment '%s' from terraform state file, could not find the resource", d.Id())
 			d.SetId("")
 			return nil
 		}
-		if _, ok := err.(*project.GetMachineDeploymentForbidden); ok {
-			k.log.Infof("removing node deployment '%s' from terraform state file, access forbidden", d.Id())
+		if apiErrorIs(err, apiErrorForbidden) {
+			k.logger(ctx).Infof("removing node deployment '%s' from terraform state file, access forbidden", d.Id())
 			d.SetId("")
 			return nil
 		}
-		return diag.Errorf("unable to get node deployment '%s/%s/%s': %s", projectID, clusterID, d.Id(), stringifyResponseError(err))
+		return diagFromAPIError(err, fmt.Sprintf("unable to get node deployment '%s/%s/%s'", projectID, clusterID, d.Id()), nil)
 	}
 
 	_ = d.Set("name", r.Payload.Name)
 
+	var labelsAll map[string]string
+	if r.Payload.Spec != nil && r.Payload.Spec.Template != nil {
+		labelsAll = r.Payload.Spec.Template.Labels
+		r.Payload.Spec.Template.Labels = labelsWithoutDefaults(labelsAll, d.Get("spec.0.template.0.labels"), k.defaultLabels)
+	}
+	_ = d.Set("labels_all", labelsAll)
+
 	_ = d.Set("spec", metakubeNodeDeploymentFlattenSpec(r.Payload.Spec))
 
 	_ = d.Set("creation_timestamp", r.Payload.CreationTimestamp.String())
@@ -224,7 +237,7 @@ func metakubeResourceNodeDeploymentUpdate(ctx context.Context, d *schema.Resourc
 	clusterID := d.Get("cluster_id").(string)
 
 	nodeDeployment := &models.NodeDeployment{
-		Spec: metakubeNodeDeploymentExpandSpec(d.Get("spec").([]interface{}), false),
+		Spec: metakubeNodeDeploymentExpandSpec(d.Get("spec").([]interface{}), false, k.defaultLabels),
 	}
 
 	if err := metakubeResourceNodeDeploymentVersionCompatibleWithCluster(ctx, k, projectID, clusterID, nodeDeployment); err != nil {
@@ -239,31 +252,15 @@ func metakubeResourceNodeDeploymentUpdate(ctx context.Context, d *schema.Resourc
 	p.SetPatch(nodeDeployment)
 	_, err := k.client.Project.PatchMachineDeployment(p, k.auth)
 	if err != nil {
-		return diag.Errorf("unable to update a node deployment: %v", stringifyResponseError(err))
+		return diagFromAPIError(err, "unable to update a node deployment", cty.GetAttrPath("spec"))
 	}
 
-	if d.HasChange("spec.0.template.0.labels") {
+	if d.HasChanges("spec.0.template.0.labels", "labels_all") {
 		// To delete a label key we have to send PATCH request with that key set to null.
 		// For simplicity we are doing it in a separate PATCH.
 
-		before, now := d.GetChange("spec.0.template.0.labels")
-
-		var beforeMap, nowMap map[string]interface{}
-		var ok bool
-
-		if before != nil {
-			beforeMap, ok = before.(map[string]interface{})
-			if !ok {
-				return diag.Errorf("failed to apply labels change")
-			}
-		}
-
-		if now != nil {
-			nowMap, ok = now.(map[string]interface{})
-			if !ok {
-				return diag.Errorf("failed to apply labels change")
-			}
-		}
+		beforeMap := labelsAllBefore(d, "spec.0.template.0.labels")
+		nowMap := labelsAll(beforeMap, d.Get("spec.0.template.0.labels"), k.defaultLabels)
 
 		labelsPatch := make(map[string]interface{})
 		for k := range beforeMap {
@@ -287,24 +284,25 @@ func metakubeResourceNodeDeploymentUpdate(ctx context.Context, d *schema.Resourc
 			p.SetMachineDeploymentID(d.Id())
 			p.SetPatch(&patch)
 
-			err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutUpdate), func() *retry.RetryError {
+			err := retryContext(ctx, d.Timeout(schema.TimeoutUpdate), "patch node deployment labels", func(ctx context.Context) *retry.RetryError {
 				_, err := k.client.Project.PatchMachineDeployment(p, k.auth)
 				if err != nil {
-					if strings.Contains(stringifyResponseError(err), "the object has been modified") {
-						return retry.RetryableError(fmt.Errorf("machine deployment patch conflict: %v", err))
+					e := newAPIError(err)
+					if e.kind == apiErrorConflict {
+						return retry.RetryableError(fmt.Errorf("machine deployment patch conflict: %w", e))
 					}
-					return retry.NonRetryableError(fmt.Errorf("patch machine deployment '%s': %v", d.Id(), err))
+					return retry.NonRetryableError(fmt.Errorf("patch machine deployment '%s': %w", d.Id(), e))
 				}
 				return nil
 			})
 			if err != nil {
-				return diag.Errorf("unable to update a node deployment: %v", stringifyResponseError(err))
+				return diagFromAPIError(err, "unable to update a node deployment", cty.GetAttrPath("spec"))
 			}
 		}
 	}
 
 	if err := metakubeResourceNodeDeploymentWaitForReady(ctx, k, d.Timeout(schema.TimeoutUpdate), projectID, clusterID, d.Id()); err != nil {
-		return diag.FromErr(err)
+		return notReadyDiagnostics(err, diag.Error, fmt.Sprintf("node deployment '%s' is not ready", d.Id()))
 	}
 
 	return metakubeResourceNodeDeploymentRead(ctx, d, m)
@@ -363,10 +361,7 @@ func validateKubeletVersionIsAvailable(k *metakubeProviderMeta, kubeletVersion,
 	r, err := k.client.Versions.GetNodeUpgrades(p, k.auth)
 
 	if err != nil {
-		if e, ok := err.(*versions.GetNodeUpgradesDefault); ok && e.Payload != nil && e.Payload.Error != nil && e.Payload.Error.Message != nil {
-			return fmt.Errorf("get node_deployment upgrades: %s", *e.Payload.Error.Message)
-		}
-		return err
+		return fmt.Errorf("get node_deployment upgrades: %w", newAPIError(err))
 	}
 
 	var availableVersions []string
@@ -381,7 +376,8 @@ func validateKubeletVersionIsAvailable(k *metakubeProviderMeta, kubeletVersion,
 }
 
 func metakubeResourceNodeDeploymentWaitForReady(ctx context.Context, k *metakubeProviderMeta, timeout time.Duration, projectID, clusterID, id string) error {
-	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+	var last notReadyError
+	err := retryContext(ctx, timeout, "wait for node deployment", func(ctx context.Context) *retry.RetryError {
 		p := project.NewGetMachineDeploymentParams().
 			WithContext(ctx).
 			WithProjectID(projectID).
@@ -390,11 +386,12 @@ func metakubeResourceNodeDeploymentWaitForReady(ctx context.Context, k *metakube
 
 		r, err := k.client.Project.GetMachineDeployment(p, k.auth)
 		if err != nil {
-			return retry.RetryableError(fmt.Errorf("unable to get node deployment %s", stringifyResponseError(err)))
+			return retry.RetryableError(fmt.Errorf("unable to get node deployment %w", newAPIError(err)))
 		}
+		last.replicas, last.status = r.Payload.Spec.Replicas, r.Payload.Status
 
 		if r.Payload.Spec.Replicas == nil || r.Payload.Status == nil || r.Payload.Status.ReadyReplicas < *r.Payload.Spec.Replicas || r.Payload.Status.UnavailableReplicas != 0 {
-			k.log.Debugf("waiting for node deployment '%s' to be ready, %+v", id, r.Payload.Status)
+			k.logger(ctx).Debugf("waiting for node deployment '%s' to be ready, %+v", id, r.Payload.Status)
 			return retry.RetryableError(fmt.Errorf("waiting for node deployment '%s' to be ready", id))
 		}
 
@@ -406,20 +403,27 @@ func metakubeResourceNodeDeploymentWaitForReady(ctx context.Context, k *metakube
 			WithMachineDeploymentID(id)
 		r2, err := k.client.Project.ListMachineDeploymentNodes(p2, k.auth)
 		if err != nil {
-			return retry.RetryableError(fmt.Errorf("unable to list nodes %s", stringifyResponseError(err)))
+			return retry.RetryableError(fmt.Errorf("unable to list nodes %w", newAPIError(err)))
 		}
+		last.nodes = r2.Payload
 		if len(r2.Payload) != int(*r.Payload.Spec.Replicas) {
-			k.log.Debug("node count mismatch, want %v got %v", *r.Payload.Spec.Replicas, len(r2.Payload))
+			k.logger(ctx).Debug("node count mismatch, want %v got %v", *r.Payload.Spec.Replicas, len(r2.Payload))
 			return retry.RetryableError(fmt.Errorf("want %v nodes, got %v", *r.Payload.Spec.Replicas, len(r2.Payload)))
 		}
 		for _, node := range r2.Payload {
 			if node.Status == nil || node.Status.NodeInfo == nil || node.Status.NodeInfo.KernelVersion == "" {
-				k.log.Debug("found not ready node")
+				k.logger(ctx).Debug("found not ready node")
 				return retry.RetryableError(fmt.Errorf("some nodes are not ready"))
 			}
 		}
 		return nil
 	})
+	if err != nil {
+		last.err = err
+		last.events = metakubeNodeDeploymentEvents(ctx, k, projectID, clusterID, id)
+		return &last
+	}
+	return nil
 }
 
 func metakubeResourceNodeDeploymentDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -427,21 +431,22 @@ func metakubeResourceNodeDeploymentDelete(ctx context.Context, d *schema.Resourc
 	projectID := d.Get("project_id").(string)
 	clusterID := d.Get("cluster_id").(string)
 	p := project.NewDeleteMachineDeploymentParams().
+		WithContext(ctx).
 		WithProjectID(projectID).
 		WithClusterID(clusterID).
 		WithMachineDeploymentID(d.Id())
 
 	_, err := k.client.Project.DeleteMachineDeployment(p, k.auth)
 	if err != nil {
-		if e, ok := err.(*project.DeleteMachineDeploymentDefault); ok && e.Code() == http.StatusNotFound {
-			k.log.Infof("removing node deployment '%s' from terraform state file, could not find the resource", d.Id())
+		if apiErrorIs(err, apiErrorNotFound) {
+			k.logger(ctx).Infof("removing node deployment '%s' from terraform state file, could not find the resource", d.Id())
 			d.SetId("")
 			return nil
 		}
-		return diag.Errorf("unable to delete node deployment '%s': %s", d.Id(), stringifyResponseError(err))
+		return diagFromAPIError(err, fmt.Sprintf("unable to delete node deployment '%s'", d.Id()), nil)
 	}
 
-	err = retry.RetryContext(ctx, d.Timeout(schema.TimeoutDelete), func() *retry.RetryError {
+	err = retryContext(ctx, d.Timeout(schema.TimeoutDelete), "wait for node deployment deletion", func(ctx context.Context) *retry.RetryError {
 		p := project.NewGetMachineDeploymentParams().
 			WithContext(ctx).
 			WithProjectID(projectID).
@@ -450,15 +455,15 @@ func metakubeResourceNodeDeploymentDelete(ctx context.Context, d *schema.Resourc
 
 		r, err := k.client.Project.GetMachineDeployment(p, k.auth)
 		if err != nil {
-			if e, ok := err.(*project.GetMachineDeploymentDefault); ok && e.Code() == http.StatusNotFound {
-				k.log.Debugf("node deployment '%s' has been destroyed, returned http code: %d", d.Id(), e.Code())
+			if e := newAPIError(err); e.kind == apiErrorNotFound {
+				k.logger(ctx).Debugf("node deployment '%s' has been destroyed, returned http code: %d", d.Id(), e.status)
 				d.SetId("")
 				return nil
 			}
-			return retry.NonRetryableError(fmt.Errorf("unable to get node deployment '%s': %s", d.Id(), stringifyResponseError(err)))
+			return retry.NonRetryableError(fmt.Errorf("unable to get node deployment '%s': %w", d.Id(), newAPIError(err)))
 		}
 
-		k.log.Debugf("node deployment '%s' deletion in progress, deletionTimestamp: %s",
+		k.logger(ctx).Debugf("node deployment '%s' deletion in progress, deletionTimestamp: %s",
 			d.Id(), r.Payload.DeletionTimestamp.String())
 		return retry.RetryableError(fmt.Errorf("node deployment '%s' deletion in progress", d.Id()))
 	})
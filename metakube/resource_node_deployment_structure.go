@@ -287,7 +287,7 @@ func metakubeNodeDeploymentFlattenAzureSpec(in *models.AzureNodeSpec) []interfac
 
 // expanders
 
-func metakubeNodeDeploymentExpandSpec(p []interface{}, isCreate bool) *models.NodeDeploymentSpec {
+func metakubeNodeDeploymentExpandSpec(p []interface{}, isCreate bool, defaultLabels map[string]string) *models.NodeDeploymentSpec {
 	if len(p) < 1 {
 		return nil
 	}
@@ -324,14 +324,14 @@ func metakubeNodeDeploymentExpandSpec(p []interface{}, isCreate bool) *models.No
 
 	if v, ok := in["template"]; ok {
 		if vv, ok := v.([]interface{}); ok {
-			obj.Template = metakubeNodeDeploymentExpandNodeSpec(vv)
+			obj.Template = metakubeNodeDeploymentExpandNodeSpec(vv, defaultLabels)
 		}
 	}
 
 	return obj
 }
 
-func metakubeNodeDeploymentExpandNodeSpec(p []interface{}) *models.NodeSpec {
+func metakubeNodeDeploymentExpandNodeSpec(p []interface{}, defaultLabels map[string]string) *models.NodeSpec {
 	if len(p) < 1 {
 		return nil
 	}
@@ -355,6 +355,7 @@ func metakubeNodeDeploymentExpandNodeSpec(p []interface{}) *models.NodeSpec {
 			}
 		}
 	}
+	obj.Labels = mergeDefaultLabels(defaultLabels, obj.Labels)
 
 	if v, ok := in["operating_system"]; ok {
 		if vv, ok := v.([]interface{}); ok {
@@ -400,7 +400,7 @@ func TestExpandNodeDeploymentSpec(t *testing.T) {
 	}
 
 	for _, tc := range cases {
-		output := metakubeNodeDeploymentExpandSpec(tc.Input, false)
+		output := metakubeNodeDeploymentExpandSpec(tc.Input, false, nil)
 		if diff := cmp.Diff(tc.ExpectedOutput, output); diff != "" {
 			t.Fatalf("Unexpected output from expander: mismatch (-want +got):\n%s", diff)
 		}
@@ -502,7 +502,7 @@ func TestExpandNodeSpec(t *testing.T) {
 	}
 
 	for _, tc := range cases {
-		output := metakubeNodeDeploymentExpandNodeSpec(tc.Input)
+		output := metakubeNodeDeploymentExpandNodeSpec(tc.Input, nil)
 		if diff := cmp.Diff(tc.ExpectedOutput, output); diff != "" {
 			t.Fatalf("Unexpected output from expander: mismatch (-want +got):\n%s", diff)
 		}
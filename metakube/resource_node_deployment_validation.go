@@ -3,7 +3,6 @@ package metakube
 import (
 	"context"
 	"fmt"
-	"net/http"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/syseleven/go-metakube/client/project"
@@ -76,10 +75,10 @@ func metakubeGetCluster(ctx context.Context, proj, cls string, k *metakubeProvid
 		WithClusterID(cls)
 	r, err := k.client.Project.GetClusterV2(p, k.auth)
 	if err != nil {
-		if e, ok := err.(*project.GetClusterV2Default); ok && e.Code() == http.StatusNotFound {
+		if apiErrorIs(err, apiErrorNotFound) {
 			return nil, false, nil
 		}
-		return nil, false, fmt.Errorf("unable to get cluster %s in project %s - error: %v", cls, proj, err)
+		return nil, false, fmt.Errorf("unable to get cluster %s in project %s - error: %w", cls, proj, newAPIError(err))
 	}
 
 	return r.Payload, true, nil
@@ -3,7 +3,6 @@ package metakube
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
@@ -15,7 +14,7 @@ import (
 )
 
 func metakubeResourceRoleBinding() *schema.Resource {
-	return &schema.Resource{
+	return metakubeResourceWithProjectName(&schema.Resource{
 		CreateContext: metakubeResourceRoleBindingCreate,
 		ReadContext:   metakubeResourceRoleBindingRead,
 		DeleteContext: metakubeResourceRoleBindingDelete,
@@ -33,13 +32,6 @@ func metakubeResourceRoleBinding() *schema.Resource {
 		},
 
 		Schema: map[string]*schema.Schema{
-			"project_id": {
-				Type:         schema.TypeString,
-				Required:     true,
-				ValidateFunc: validation.NoZeroValues,
-				ForceNew:     true,
-				Description:  "The id of the project resource belongs to",
-			},
 			"cluster_id": {
 				Type:         schema.TypeString,
 				ValidateFunc: validation.NoZeroValues,
@@ -85,33 +77,38 @@ func metakubeResourceRoleBinding() *schema.Resource {
 				},
 			},
 		},
-	}
+	})
 }
 
 func metakubeResourceRoleBindingCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	k := m.(*metakubeProviderMeta)
+	projectID, diagnostics := metakubeResourceProjectID(ctx, d, k)
+	if diagnostics.HasError() {
+		return diagnostics
+	}
 
 	subjects := metakubeRoleBindingExpandSubjects(d.Get("subject"))
 	for _, sub := range subjects {
-		err := retry.RetryContext(ctx, d.Timeout(schema.TimeoutCreate), func() *retry.RetryError {
+		err := retryContext(ctx, d.Timeout(schema.TimeoutCreate), "bind role", func(ctx context.Context) *retry.RetryError {
 			params := project.NewBindUserToRoleV2Params().
 				WithContext(ctx).
-				WithProjectID(d.Get("project_id").(string)).
+				WithProjectID(projectID).
 				WithClusterID(d.Get("cluster_id").(string)).
 				WithNamespace(d.Get("namespace").(string)).
 				WithRoleID(d.Get("role_name").(string)).
 				WithBody(&sub)
 			_, err := k.client.Project.BindUserToRoleV2(params, k.auth)
 			if err != nil {
-				e, ok := err.(*project.BindUserToRoleV2Default)
-				if ok && (e.Code() == http.StatusConflict || e.Code() == http.StatusNotFound) {
-					return retry.RetryableError(err)
+				e := newAPIError(err)
+				if e.kind == apiErrorConflict || e.kind == apiErrorNotFound {
+					return retry.RetryableError(e)
 				}
+				return e.retryError()
 			}
 			return nil
 		})
 		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to create role bindings: %s", stringifyResponseError(err)))
+			return diagFromAPIError(err, "failed to create role bindings", nil)
 		}
 	}
 	d.SetId(d.Get("namespace").(string) + ":" + d.Get("role_name").(string))
@@ -127,7 +124,7 @@ func metakubeResourceRoleBindingRead(ctx context.Context, d *schema.ResourceData
 		WithClusterID(d.Get("cluster_id").(string))
 	ret, err := k.client.Project.ListRoleBindingV2(params, k.auth)
 	if err != nil {
-		return diag.FromErr(fmt.Errorf("failed to list role bindings: %s", stringifyResponseError(err)))
+		return diagFromAPIError(err, "failed to list role bindings", nil)
 	}
 
 	idParts := strings.Split(d.Id(), ":")
@@ -174,7 +171,7 @@ func metakubeResourceRoleBindingDelete(ctx context.Context, d *schema.ResourceDa
 			WithBody(&sub)
 		_, err := k.client.Project.UnbindUserFromRoleBindingV2(params, k.auth)
 		if err != nil {
-			return diag.FromErr(fmt.Errorf("failed to delete role binding: %s", stringifyResponseError(err)))
+			return diagFromAPIError(err, "failed to delete role binding", nil)
 		}
 	}
 	return nil
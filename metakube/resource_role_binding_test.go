@@ -1,16 +1,47 @@
 package metakube
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
+func TestMetakubeResourceRoleBindingCreateError(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "forbidden"}}`))
+	}))
+	d := schema.TestResourceDataRaw(t, metakubeResourceRoleBinding().Schema, map[string]interface{}{
+		"project_id": "p1",
+		"cluster_id": "c1",
+		"namespace":  "default",
+		"role_name":  "namespace-viewer",
+		"subject": []interface{}{
+			map[string]interface{}{"kind": "user", "name": "foo.bar@mycompany.xyz"},
+		},
+	})
+
+	diagnostics := metakubeResourceRoleBindingCreate(context.Background(), d, k)
+	if !diagnostics.HasError() {
+		t.Fatal("want error when binding the role fails")
+	}
+	if !strings.Contains(diagnostics[0].Summary, "failed to create role bindings") {
+		t.Fatalf("want the API error, got %q", diagnostics[0].Summary)
+	}
+	if d.Id() != "" {
+		t.Fatalf("want no resource created, got id %s", d.Id())
+	}
+}
+
 func TestAccMetakubeRoleBinding(t *testing.T) {
 	t.Parallel()
 	resourceName := "metakube_role_binding.acctest"
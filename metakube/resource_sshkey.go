@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
@@ -15,7 +16,7 @@ import (
 )
 
 func metakubeResourceSSHKey() *schema.Resource {
-	return &schema.Resource{
+	return metakubeResourceWithProjectName(&schema.Resource{
 		CreateContext: metakubeResourceSSHKeyCreate,
 		ReadContext:   metakubeResourceSSHKeyRead,
 		DeleteContext: metakubeResourceSSHKeyDelete,
@@ -24,13 +25,6 @@ func metakubeResourceSSHKey() *schema.Resource {
 		},
 
 		Schema: map[string]*schema.Schema{
-			"project_id": {
-				Type:         schema.TypeString,
-				Required:     true,
-				ValidateFunc: validation.NoZeroValues,
-				ForceNew:     true,
-			},
-
 			"name": {
 				Type:         schema.TypeString,
 				Required:     true,
@@ -48,13 +42,18 @@ func metakubeResourceSSHKey() *schema.Resource {
 				ForceNew: true,
 			},
 		},
-	}
+	})
 }
 
 func metakubeResourceSSHKeyCreate(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
 	k := m.(*metakubeProviderMeta)
+	projectID, diagnostics := metakubeResourceProjectID(ctx, d, k)
+	if diagnostics.HasError() {
+		return diagnostics
+	}
 	p := project.NewCreateSSHKeyParams()
-	p.SetProjectID(d.Get("project_id").(string))
+	p.SetContext(ctx)
+	p.SetProjectID(projectID)
 	p.Key = &models.SSHKey{
 		Name: d.Get("name").(string),
 		Spec: &models.SSHKeySpec{
@@ -63,7 +62,7 @@ func metakubeResourceSSHKeyCreate(ctx context.Context, d *schema.ResourceData, m
 	}
 	created, err := k.client.Project.CreateSSHKey(p, k.auth)
 	if err != nil {
-		return diag.Errorf("unable to create SSH key: %s", stringifyResponseError(err))
+		return diagFromAPIError(err, "unable to create SSH key", cty.GetAttrPath("public_key"))
 	}
 	d.SetId(created.Payload.ID)
 	return metakubeResourceSSHKeyRead(ctx, d, m)
@@ -111,10 +110,10 @@ func metakubeResourceSSHKeyFindByID(ctx context.Context, d *schema.ResourceData,
 			res, err := meta.client.Project.ListSSHKeys(prms, meta.auth)
 			if err != nil {
 				// wait for the RBACs
-				if _, ok := err.(*project.ListSSHKeysForbidden); ok {
+				if apiErrorIs(err, apiErrorForbidden) {
 					return res, pending, nil
 				}
-				return nil, pending, fmt.Errorf("list ssh keys: %s", stringifyResponseError(err))
+				return nil, pending, fmt.Errorf("list ssh keys: %w", newAPIError(err))
 			}
 			return res, target, nil
 		},
@@ -123,7 +122,7 @@ func metakubeResourceSSHKeyFindByID(ctx context.Context, d *schema.ResourceData,
 	}
 	s, err := listStateConf.WaitForStateContext(ctx)
 	if err != nil {
-		meta.log.Debugf("error while waiting for the SSH keys: %v", err)
+		meta.logger(ctx).Debugf("error while waiting for the SSH keys: %v", err)
 		return nil, fmt.Errorf("error while waiting for the SSH keys: %v", err)
 	}
 	keys := s.(*project.ListSSHKeysOK)
@@ -138,39 +137,14 @@ func metakubeResourceSSHKeyFindByID(ctx context.Context, d *schema.ResourceData,
 }
 
 func metakubeResourceSSHKeyFindProjectID(ctx context.Context, id string, meta *metakubeProviderMeta) (string, error) {
-	r, err := meta.client.Project.ListProjects(project.NewListProjectsParams(), meta.auth)
+	projectID, err := meta.projects.findSSHKey(ctx, id, meta)
 	if err != nil {
-		return "", fmt.Errorf("list projects: %v", err)
+		return "", err
 	}
-
-	for _, project := range r.Payload {
-		ok, err := metakubeResourceSSHKeyBelongsToProject(ctx, project.ID, id, meta)
-		if ok {
-			return project.ID, nil
-		}
-		if err != nil {
-			return "", err
-		}
+	if projectID == "" {
+		meta.logger(ctx).Infof("owner project for service account with id(%s) not found", id)
 	}
-
-	meta.log.Infof("owner project for service account with id(%s) not found", id)
-	return "", nil
-}
-
-func metakubeResourceSSHKeyBelongsToProject(ctx context.Context, prj, id string, meta *metakubeProviderMeta) (bool, error) {
-	prms := project.NewListSSHKeysParams().WithContext(ctx).WithProjectID(prj)
-	r, err := meta.client.Project.ListSSHKeys(prms, meta.auth)
-	if err != nil {
-		return false, fmt.Errorf("list sshkeys: %s", stringifyResponseError(err))
-	}
-
-	for _, i := range r.Payload {
-		if i.ID == id {
-			return true, nil
-		}
-	}
-
-	return false, nil
+	return projectID, nil
 }
 
 func metakubeResourceSSHKeyDelete(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
@@ -181,7 +155,7 @@ func metakubeResourceSSHKeyDelete(ctx context.Context, d *schema.ResourceData, m
 	p.SetSSHKeyID(d.Id())
 	_, err := k.client.Project.DeleteSSHKey(p, k.auth)
 	if err != nil {
-		return diag.Errorf("unable to delete SSH key: %s", stringifyResponseError(err))
+		return diagFromAPIError(err, "unable to delete SSH key", nil)
 	}
 	return nil
 }
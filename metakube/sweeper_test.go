@@ -2,6 +2,7 @@ package metakube
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"go.uber.org/zap"
@@ -9,19 +10,20 @@ import (
 
 func sharedConfigForRegion(_ string) (*metakubeProviderMeta, error) {
 	host := os.Getenv("METAKUBE_HOST")
-	client, err := newClient(host)
-	if err != nil {
-		return nil, fmt.Errorf("create client %v", err)
+	client, diagnostics := newClient(host, http.DefaultTransport, authContextMiddleware)
+	if diagnostics.HasError() {
+		return nil, fmt.Errorf("create client %v", diagnostics)
 	}
-	token := os.Getenv("METAKUBE_TOKEN")
-	auth, err := newAuth(token, "", "")
-	if err != nil {
-		return nil, fmt.Errorf("auth api %v", err)
+	src, diagnostics := newStaticTokenSource(os.Getenv("METAKUBE_TOKEN"), "")
+	if diagnostics.HasError() {
+		return nil, fmt.Errorf("auth api %v", diagnostics)
 	}
 	log := zap.NewNop().Sugar()
 	return &metakubeProviderMeta{
-		client,
-		auth,
-		log,
+		client:   client,
+		auth:     newTokenSourceAuth(src, ""),
+		log:      log,
+		limiter:  newAPILimiter(0, 0),
+		projects: newProjectIndex(),
 	}, nil
 }
@@ -0,0 +1,226 @@
+package metakube
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/mitchellh/go-homedir"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	tracerName = "github.com/syseleven/terraform-provider-metakube/metakube"
+
+	tracingExporterOTLP = "otlp"
+	tracingExporterFile = "file"
+
+	// time given to export spans at the end of every operation
+	tracingFlushTimeout = 5 * time.Second
+)
+
+// newTracerProvider returns the tracer provider configured by the tracing
+// block, or nil if tracing is disabled.
+func newTracerProvider(d *schema.ResourceData) (*sdktrace.TracerProvider, diag.Diagnostics) {
+	v := d.Get("tracing").([]interface{})
+	if len(v) == 0 || v[0] == nil {
+		return nil, nil
+	}
+	m := v[0].(map[string]interface{})
+
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch m["exporter"].(string) {
+	case tracingExporterFile:
+		exporter, err = newTracingFileExporter(m["file_path"].(string))
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't create trace file: %v", err),
+				AttributePath: cty.GetAttrPath("tracing").IndexInt(0).GetAttr("file_path"),
+			}}
+		}
+	default:
+		opts, diagnostics := newTracingOTLPOptions(m)
+		if diagnostics.HasError() {
+			return nil, diagnostics
+		}
+		// the exporter connects lazily, creating it doesn't block
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't create OTLP exporter: %v", err),
+				AttributePath: cty.GetAttrPath("tracing"),
+			}}
+		}
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("terraform-provider-metakube"),
+		)),
+	), nil
+}
+
+func newTracingFileExporter(path string) (sdktrace.SpanExporter, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file_path is required for the file exporter")
+	}
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return stdouttrace.New(stdouttrace.WithWriter(f))
+}
+
+func newTracingOTLPOptions(m map[string]interface{}) ([]otlptracehttp.Option, diag.Diagnostics) {
+	var opts []otlptracehttp.Option
+	if endpoint := m["endpoint"].(string); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't parse endpoint '%s', want an URL like https://collector:4318", endpoint),
+				AttributePath: cty.GetAttrPath("tracing").IndexInt(0).GetAttr("endpoint"),
+			}}
+		}
+		opts = append(opts, otlptracehttp.WithEndpoint(u.Host))
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		if u.Path != "" && u.Path != "/" {
+			opts = append(opts, otlptracehttp.WithURLPath(u.Path))
+		}
+	}
+	if headers, ok := m["headers"].(map[string]interface{}); ok && len(headers) > 0 {
+		h := make(map[string]string, len(headers))
+		for k, v := range headers {
+			h[k] = v.(string)
+		}
+		opts = append(opts, otlptracehttp.WithHeaders(h))
+	}
+	return opts, nil
+}
+
+func (k *metakubeProviderMeta) tracer() trace.Tracer {
+	if k.tracerProvider == nil {
+		return noop.NewTracerProvider().Tracer(tracerName)
+	}
+	return k.tracerProvider.Tracer(tracerName)
+}
+
+// flushTraces exports the spans ended so far. Terraform stops the provider
+// without notice, so spans are exported after every operation.
+func (k *metakubeProviderMeta) flushTraces() {
+	if k.tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+	defer cancel()
+	if err := k.tracerProvider.ForceFlush(ctx); err != nil {
+		k.log.Debugf("export traces: %v", err)
+	}
+}
+
+// withTracing runs f in a span named after the resource and the operation.
+func withTracing(resourceType, operation string, f crudFunc) crudFunc {
+	return func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+		k := m.(*metakubeProviderMeta)
+		ctx, span := k.tracer().Start(ctx, resourceType+"."+operation, trace.WithAttributes(
+			attribute.String("metakube.resource_type", resourceType),
+			attribute.String("metakube.operation", operation),
+			attribute.String("metakube.id", d.Id()),
+		))
+		diagnostics := f(ctx, d, m)
+		for _, v := range diagnostics {
+			if v.Severity == diag.Error {
+				span.SetStatus(codes.Error, v.Summary)
+				break
+			}
+		}
+		if d.Id() != "" {
+			span.SetAttributes(attribute.String("metakube.id", d.Id()))
+		}
+		span.End()
+		k.flushTraces()
+		return diagnostics
+	}
+}
+
+// traceMiddleware starts a span for every MetaKube API call.
+func (k *metakubeProviderMeta) traceMiddleware(next runtime.ClientTransport) runtime.ClientTransport {
+	return clientTransportFunc(func(op *runtime.ClientOperation) (interface{}, error) {
+		// without a context the client applies its request timeout, which a
+		// context carrying the span would disable
+		parent := op.Context
+		ctx := parent
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		attrs := []attribute.KeyValue{
+			semconv.HTTPMethod(op.Method),
+			attribute.String("metakube.path_pattern", op.PathPattern),
+		}
+		if req, err := newOperationRequest(op); err == nil {
+			attrs = append(attrs, attribute.String("url.path", req.path()))
+		}
+		ctx, span := k.tracer().Start(ctx, op.ID, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+		defer span.End()
+
+		if parent != nil {
+			op.Context = ctx
+		}
+		res, err := next.Submit(op)
+		if err != nil {
+			if status := apiErrorStatus(err); status != 0 {
+				span.SetAttributes(semconv.HTTPStatusCode(status))
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, newAPIError(err).Error())
+		}
+		return res, err
+	})
+}
+
+// retryContext is retry.RetryContext with a span for every attempt. The span
+// is a child of the span in ctx, f gets a context carrying the attempt's span.
+// Attempts poll the API, so their requests skip the API cache.
+func retryContext(ctx context.Context, timeout time.Duration, name string, f func(context.Context) *retry.RetryError) error {
+	tracer := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName)
+	attempt := 0
+	return retry.RetryContext(ctx, timeout, func() *retry.RetryError {
+		attempt++
+		ctx, span := tracer.Start(ctx, name, trace.WithAttributes(attribute.Int("metakube.attempt", attempt)))
+		defer span.End()
+
+		rerr := f(withoutAPICache(ctx))
+		if rerr != nil && rerr.Err != nil {
+			span.SetAttributes(attribute.Bool("metakube.retryable", rerr.Retryable))
+			span.SetStatus(codes.Error, rerr.Err.Error())
+		}
+		return rerr
+	})
+}
@@ -0,0 +1,147 @@
+package metakube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/runtime"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/retry"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/syseleven/go-metakube/client/project"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	k := &metakubeProviderMeta{
+		log:            zap.NewNop().Sugar(),
+		tracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+	}
+	k.client = newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","name":"cluster"}`))
+	}), k.traceMiddleware).client
+
+	r := &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"project_id": {Type: schema.TypeString, Optional: true},
+		},
+		ReadContext: func(ctx context.Context, d *schema.ResourceData, m interface{}) diag.Diagnostics {
+			k := m.(*metakubeProviderMeta)
+			attempts := 0
+			err := retryContext(ctx, time.Minute, "wait for cluster", func(ctx context.Context) *retry.RetryError {
+				p := project.NewGetClusterV2Params().WithContext(ctx).WithProjectID("p1").WithClusterID("c1")
+				if _, err := k.client.Project.GetClusterV2(p, nil); err != nil {
+					return retry.NonRetryableError(err)
+				}
+				attempts++
+				if attempts < 2 {
+					return retry.RetryableError(errors.New("not ready"))
+				}
+				return nil
+			})
+			return diag.FromErr(err)
+		},
+	}
+	instrumentResource("metakube_cluster", r)
+
+	d := schema.TestResourceDataRaw(t, r.Schema, map[string]interface{}{"project_id": "p1"})
+	d.SetId("c1")
+	if diags := r.ReadContext(context.Background(), d, k); diags.HasError() {
+		t.Fatalf("unexpected error: %v", diags)
+	}
+
+	spans := recorder.Ended()
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range spans {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+	if len(byName["metakube_cluster.read"]) != 1 {
+		t.Fatalf("want one span for the operation, got %v", spanNames(spans))
+	}
+	if len(byName["wait for cluster"]) != 2 {
+		t.Fatalf("want one span per attempt, got %v", spanNames(spans))
+	}
+	if len(byName["getClusterV2"]) != 2 {
+		t.Fatalf("want one span per API call, got %v", spanNames(spans))
+	}
+
+	root := byName["metakube_cluster.read"][0]
+	for i, attempt := range byName["wait for cluster"] {
+		if attempt.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("attempt span should be a child of the operation span")
+		}
+		if call := byName["getClusterV2"][i]; call.Parent().SpanID() != attempt.SpanContext().SpanID() {
+			t.Errorf("API call span should be a child of the attempt span")
+		}
+	}
+	if byName["wait for cluster"][0].Status().Code != codes.Error {
+		t.Errorf("want failed attempt to be marked as error")
+	}
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) []string {
+	var ret []string
+	for _, s := range spans {
+		ret = append(ret, s.Name())
+	}
+	return ret
+}
+
+func TestTracingCacheHits(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	k := &metakubeProviderMeta{tracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))}
+	k.cache = newAPICache()
+	requests := 0
+	// same order as in configure, the trace middleware wraps the cache
+	client := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","name":"cluster"}`))
+	}), authContextMiddleware, k.cache.middleware, k.traceMiddleware).client
+
+	for i := 0; i < 2; i++ {
+		p := project.NewGetClusterV2Params().WithContext(context.Background()).WithProjectID("p1").WithClusterID("c1")
+		if _, err := client.Project.GetClusterV2(p, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("want the second call answered by the cache, got %d requests", requests)
+	}
+	if got := len(recorder.Ended()); got != 2 {
+		t.Fatalf("want a span for every call, got %d", got)
+	}
+}
+
+func TestTraceMiddlewareContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	k := &metakubeProviderMeta{tracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))}
+	var got context.Context
+	next := clientTransportFunc(func(op *runtime.ClientOperation) (interface{}, error) {
+		got = op.Context
+		return nil, nil
+	})
+
+	_, _ = k.traceMiddleware(next).Submit(&runtime.ClientOperation{ID: "getProject", Method: http.MethodGet, PathPattern: "/api/v1/projects/{project_id}"})
+	if got != nil {
+		t.Fatal("want no context, so that the client applies its request timeout")
+	}
+
+	ctx := context.Background()
+	_, _ = k.traceMiddleware(next).Submit(&runtime.ClientOperation{ID: "getProject", Method: http.MethodGet, PathPattern: "/api/v1/projects/{project_id}", Context: ctx})
+	if got == nil || !trace.SpanContextFromContext(got).IsValid() {
+		t.Fatal("want context with the span of the API call")
+	}
+	if len(recorder.Ended()) != 2 {
+		t.Fatalf("want 2 spans, got %d", len(recorder.Ended()))
+	}
+}
@@ -0,0 +1,203 @@
+package metakube
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"go.uber.org/zap"
+)
+
+// newTransport builds the HTTP transport used for all MetaKube API requests.
+func newTransport(d *schema.ResourceData, base http.RoundTripper, src tokenSource, limiter *apiLimiter, log *zap.SugaredLogger, trace *zap.Logger) http.RoundTripper {
+	rt := newTraceTransport(base, trace)
+	rt = limiter.transport(rt)
+	rt = newRetryTransport(rt, d.Get("max_retries").(int), time.Duration(d.Get("retry_max_wait").(int))*time.Second, log)
+	return newReauthTransport(src, rt)
+}
+
+// invalidatingTokenSource is implemented by token sources able to obtain
+// a new token once the current one was rejected.
+type invalidatingTokenSource interface {
+	tokenSource
+	invalidate()
+}
+
+// reauthTransport repeats requests rejected with 401 Unauthorized once,
+// after fetching a new token from the token source.
+type reauthTransport struct {
+	next http.RoundTripper
+	src  invalidatingTokenSource
+}
+
+func newReauthTransport(src tokenSource, next http.RoundTripper) http.RoundTripper {
+	if s, ok := src.(invalidatingTokenSource); ok {
+		return &reauthTransport{next: next, src: s}
+	}
+	return next
+}
+
+func (t *reauthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+
+	t.src.invalidate()
+	token, err := t.src.Token(req.Context())
+	if err != nil {
+		return res, nil
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return res, nil
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+	_, _ = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	return t.next.RoundTrip(retry)
+}
+
+const (
+	retryMinWait = time.Second
+)
+
+// retryTransport repeats requests that failed because of a temporary
+// problem. Idempotent requests are repeated on connection errors and on
+// 429, 502, 503 and 504 replies. All other requests are repeated only if
+// the connection could not be established, so they were never sent.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	minWait    time.Duration
+	maxWait    time.Duration
+	log        *zap.SugaredLogger
+}
+
+func newRetryTransport(next http.RoundTripper, maxRetries int, maxWait time.Duration, log *zap.SugaredLogger) http.RoundTripper {
+	if maxRetries <= 0 {
+		return next
+	}
+	return &retryTransport{
+		next:       next,
+		maxRetries: maxRetries,
+		minWait:    retryMinWait,
+		maxWait:    maxWait,
+		log:        log,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+
+		res, err := t.next.RoundTrip(r)
+		if attempt >= t.maxRetries || !t.shouldRetry(req, res, err) {
+			return res, err
+		}
+
+		wait := t.backoff(attempt, res)
+		if res != nil {
+			loggerFromContext(req.Context(), t.log).Debugf("%s %s: retrying in %s, got %s", req.Method, req.URL.Path, wait, res.Status)
+			_, _ = io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		} else {
+			loggerFromContext(req.Context(), t.log).Debugf("%s %s: retrying in %s, got %v", req.Method, req.URL.Path, wait, err)
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (t *retryTransport) shouldRetry(req *http.Request, res *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// the body can't be sent again
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return isIdempotentRequest(req) || isConnectError(err)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotentRequest(req)
+	}
+	return false
+}
+
+// backoff returns the time to wait before the next attempt. Retry-After
+// sent by the API takes precedence over exponential backoff with jitter.
+func (t *retryTransport) backoff(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if wait, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+			if wait > t.maxWait {
+				return t.maxWait
+			}
+			return wait
+		}
+	}
+
+	wait := t.minWait << uint(attempt)
+	if wait > t.maxWait || wait <= 0 {
+		wait = t.maxWait
+	}
+	// full jitter over the upper half of the interval
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		wait := time.Until(t)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func isIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isConnectError reports whether the request failed while connecting to the
+// API, before anything was sent.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
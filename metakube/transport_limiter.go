@@ -0,0 +1,96 @@
+package metakube
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// apiLimiter throttles requests to the MetaKube API. It limits the request
+// rate with a token bucket and the number of requests in flight with a
+// semaphore. Both limits are shared by all resources of a provider.
+type apiLimiter struct {
+	rate     *rate.Limiter
+	inFlight chan struct{}
+}
+
+// newAPILimiter returns a limiter allowing requestsPerSecond requests per
+// second and maxConcurrent requests at once. Zero disables the limit.
+func newAPILimiter(requestsPerSecond float64, maxConcurrent int) *apiLimiter {
+	var l apiLimiter
+	if requestsPerSecond > 0 {
+		burst := int(math.Ceil(requestsPerSecond))
+		l.rate = rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+	}
+	if maxConcurrent > 0 {
+		l.inFlight = make(chan struct{}, maxConcurrent)
+	}
+	return &l
+}
+
+// acquire blocks until a request may be sent. The returned function must be
+// called once the request completed.
+func (l *apiLimiter) acquire(ctx context.Context) (func(), error) {
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	}
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+func (l *apiLimiter) transport(next http.RoundTripper) http.RoundTripper {
+	if l.rate == nil && l.inFlight == nil {
+		return next
+	}
+	return &limitTransport{next: next, limiter: l}
+}
+
+type limitTransport struct {
+	next    http.RoundTripper
+	limiter *apiLimiter
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// the request is in flight until its body is consumed
+	res.Body = &releaseOnClose{ReadCloser: res.Body, release: release}
+	return res, nil
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
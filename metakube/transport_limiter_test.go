@@ -0,0 +1,88 @@
+package metakube
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestAPILimiterConcurrency(t *testing.T) {
+	var cur, max int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: newAPILimiter(0, 2).transport(http.DefaultTransport)}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if max > 2 {
+		t.Fatalf("want at most 2 requests in flight, got %d", max)
+	}
+}
+
+func TestAPILimiterRate(t *testing.T) {
+	l := newAPILimiter(20, 0)
+	start := time.Now()
+	// the first 20 requests use up the burst, the next 10 are spread over half a second
+	for i := 0; i < 30; i++ {
+		release, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("requests were not throttled, took %s", elapsed)
+	}
+}
+
+func TestAPILimiterCanceled(t *testing.T) {
+	l := newAPILimiter(0, 1)
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); err == nil {
+		t.Fatal("expected an error when the context is done while waiting")
+	}
+}
+
+func TestAPILimiterDisabled(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{})
+	l := newAPILimiter(d.Get("requests_per_second").(float64), d.Get("max_concurrent_requests").(int))
+	if rt := l.transport(http.DefaultTransport); rt != http.DefaultTransport {
+		t.Fatal("limiter should be disabled by default")
+	}
+}
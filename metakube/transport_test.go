@@ -0,0 +1,203 @@
+package metakube
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syseleven/go-metakube/client/project"
+	"go.uber.org/zap"
+)
+
+type testTokenSource struct {
+	tokens []string
+}
+
+func (s *testTokenSource) Token(_ context.Context) (string, error) {
+	return s.tokens[0], nil
+}
+
+func (s *testTokenSource) invalidate() {
+	s.tokens = s.tokens[1:]
+}
+
+func TestReauthTransport(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	src := &testTokenSource{tokens: []string{"stale", "fresh"}}
+	client := &http.Client{Transport: newReauthTransport(src, http.DefaultTransport)}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"name":"test"}`))
+	req.Header.Set("Authorization", "Bearer stale")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("want status 200, got %d", res.StatusCode)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"name":"test"}` {
+		t.Fatalf("request body was not replayed: %v", bodies)
+	}
+}
+
+func TestReauthTransportStaticToken(t *testing.T) {
+	rt := newReauthTransport(staticTokenSource("token"), http.DefaultTransport)
+	if rt != http.DefaultTransport {
+		t.Fatal("static tokens can't be refreshed, transport should not be wrapped")
+	}
+}
+
+func newTestRetryTransport(maxRetries int) *retryTransport {
+	return &retryTransport{
+		next:       http.DefaultTransport,
+		maxRetries: maxRetries,
+		minWait:    time.Millisecond,
+		maxWait:    10 * time.Millisecond,
+		log:        zap.NewNop().Sugar(),
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	cases := []struct {
+		Method       string
+		Statuses     []int
+		WantStatus   int
+		WantRequests int
+	}{
+		{http.MethodGet, []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, http.StatusOK, 3},
+		{http.MethodGet, []int{http.StatusTooManyRequests, http.StatusOK}, http.StatusOK, 2},
+		{http.MethodDelete, []int{http.StatusGatewayTimeout, http.StatusOK}, http.StatusOK, 2},
+		{http.MethodGet, []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}, http.StatusServiceUnavailable, 4},
+		{http.MethodGet, []int{http.StatusInternalServerError, http.StatusOK}, http.StatusInternalServerError, 1},
+		{http.MethodGet, []int{http.StatusNotFound, http.StatusOK}, http.StatusNotFound, 1},
+		{http.MethodPost, []int{http.StatusServiceUnavailable, http.StatusOK}, http.StatusServiceUnavailable, 1},
+		{http.MethodPatch, []int{http.StatusTooManyRequests, http.StatusOK}, http.StatusTooManyRequests, 1},
+	}
+
+	for _, tc := range cases {
+		var requests int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			if r.Method != http.MethodGet && r.Method != http.MethodDelete && string(b) != "body" {
+				t.Errorf("%s: request body was not replayed, got %q", tc.Method, b)
+			}
+			w.WriteHeader(tc.Statuses[requests])
+			requests++
+		}))
+
+		var body io.Reader
+		if tc.Method != http.MethodGet && tc.Method != http.MethodDelete {
+			body = strings.NewReader("body")
+		}
+		req, _ := http.NewRequest(tc.Method, srv.URL, body)
+		res, err := (&http.Client{Transport: newTestRetryTransport(3)}).Do(req)
+		srv.Close()
+		if err != nil {
+			t.Fatalf("%s %v: unexpected error: %v", tc.Method, tc.Statuses, err)
+		}
+		if res.StatusCode != tc.WantStatus {
+			t.Errorf("%s %v: want status %d, got %d", tc.Method, tc.Statuses, tc.WantStatus, res.StatusCode)
+		}
+		if requests != tc.WantRequests {
+			t.Errorf("%s %v: want %d requests, got %d", tc.Method, tc.Statuses, tc.WantRequests, requests)
+		}
+	}
+}
+
+func TestRetryTransportConnectionError(t *testing.T) {
+	// grab a free port and close the listener, so connections are refused
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var attempts int
+	rt := newTestRetryTransport(2)
+	rt.next = roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		attempts++
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	req, _ := http.NewRequest(http.MethodPost, "http://"+addr, strings.NewReader("body"))
+	if _, err := rt.RoundTrip(req); err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 3 {
+		t.Fatalf("POST should be retried on connection errors, want 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryTransportBackoff(t *testing.T) {
+	rt := &retryTransport{minWait: time.Second, maxWait: 30 * time.Second}
+
+	res := &http.Response{Header: http.Header{"Retry-After": []string{"7"}}}
+	if wait := rt.backoff(0, res); wait != 7*time.Second {
+		t.Errorf("want Retry-After of 7s, got %s", wait)
+	}
+	res.Header.Set("Retry-After", "120")
+	if wait := rt.backoff(0, res); wait != 30*time.Second {
+		t.Errorf("Retry-After should be capped at 30s, got %s", wait)
+	}
+
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second} {
+		wait := rt.backoff(attempt, nil)
+		if wait < max/2 || wait > max {
+			t.Errorf("attempt %d: want wait between %s and %s, got %s", attempt, max/2, max, wait)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// blockingTokenSource waits for its context, like a pending device login.
+type blockingTokenSource struct{}
+
+func (blockingTokenSource) Token(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestTokenSourceAuthContext(t *testing.T) {
+	k := newTestProviderMeta(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}), authContextMiddleware)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := k.client.Project.ListProjects(project.NewListProjectsParams().WithContext(ctx), newTokenSourceAuth(blockingTokenSource{}, ""))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want deadline exceeded, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("getting the token was not cancelled with the request")
+	}
+}
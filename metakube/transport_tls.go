@@ -0,0 +1,157 @@
+package metakube
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// newBaseTransport returns the transport that connects to the MetaKube API,
+// configured with the TLS and proxy settings of the provider.
+func newBaseTransport(d *schema.ResourceData) (*http.Transport, diag.Diagnostics) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, diagnostics := newTLSConfig(d)
+	if diagnostics.HasError() {
+		return nil, diagnostics
+	}
+	t.TLSClientConfig = tlsConfig
+
+	proxy, diagnostics := newProxyFunc(d.Get("proxy_url").(string), d.Get("no_proxy").(string))
+	if diagnostics.HasError() {
+		return nil, diagnostics
+	}
+	t.Proxy = proxy
+
+	return t, nil
+}
+
+func newTLSConfig(d *schema.ResourceData) (*tls.Config, diag.Diagnostics) {
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+	}
+
+	caFile := d.Get("ca_file").(string)
+	caPEM := d.Get("ca_pem").(string)
+	if caFile != "" || caPEM != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if caFile != "" {
+			raw, err := readPath(caFile)
+			if err != nil {
+				return nil, diag.Diagnostics{{
+					Severity:      diag.Error,
+					Summary:       fmt.Sprintf("Can't read CA file: %v", err),
+					AttributePath: cty.GetAttrPath("ca_file"),
+				}}
+			}
+			if !pool.AppendCertsFromPEM(raw) {
+				return nil, diag.Diagnostics{{
+					Severity:      diag.Error,
+					Summary:       "No PEM encoded certificates found in CA file",
+					AttributePath: cty.GetAttrPath("ca_file"),
+				}}
+			}
+		}
+		if caPEM != "" && !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       "No PEM encoded certificates found",
+				AttributePath: cty.GetAttrPath("ca_pem"),
+			}}
+		}
+		config.RootCAs = pool
+	}
+
+	clientCert := d.Get("client_cert").(string)
+	clientKey := d.Get("client_key").(string)
+	if clientCert != "" || clientKey != "" {
+		certPEM, err := readPEMOrPath(clientCert)
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't read client certificate: %v", err),
+				AttributePath: cty.GetAttrPath("client_cert"),
+			}}
+		}
+		keyPEM, err := readPEMOrPath(clientKey)
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't read client key: %v", err),
+				AttributePath: cty.GetAttrPath("client_key"),
+			}}
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Invalid client certificate: %v", err),
+				AttributePath: cty.GetAttrPath("client_cert"),
+			}}
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// newProxyFunc returns the proxy selection used by the transport. Without
+// proxy_url the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables apply, no_proxy overrides NO_PROXY in both cases.
+func newProxyFunc(proxyURL, noProxy string) (func(*http.Request) (*url.URL, error), diag.Diagnostics) {
+	if proxyURL == "" && noProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	config := httpproxy.FromEnvironment()
+	if proxyURL != "" {
+		if _, err := url.Parse(proxyURL); err != nil {
+			return nil, diag.Diagnostics{{
+				Severity:      diag.Error,
+				Summary:       fmt.Sprintf("Can't parse proxy url: %v", err),
+				AttributePath: cty.GetAttrPath("proxy_url"),
+			}}
+		}
+		config.HTTPProxy = proxyURL
+		config.HTTPSProxy = proxyURL
+	}
+	if noProxy != "" {
+		config.NoProxy = noProxy
+	}
+
+	proxy := config.ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return proxy(r.URL)
+	}, nil
+}
+
+// readPEMOrPath returns v if it contains PEM encoded data, otherwise v is
+// treated as a path to read the data from.
+func readPEMOrPath(v string) ([]byte, error) {
+	if strings.Contains(v, "-----BEGIN") {
+		return []byte(v), nil
+	}
+	return readPath(v)
+}
+
+func readPath(path string) ([]byte, error) {
+	p, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(p)
+}
@@ -0,0 +1,76 @@
+package metakube
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestNewBaseTransportCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	cases := []struct {
+		Name    string
+		Raw     map[string]interface{}
+		WantErr bool
+	}{
+		{"system roots", map[string]interface{}{}, true},
+		{"ca_pem", map[string]interface{}{"ca_pem": caPEM}, false},
+		{"insecure_skip_verify", map[string]interface{}{"insecure_skip_verify": true}, false},
+	}
+	for _, tc := range cases {
+		d := schema.TestResourceDataRaw(t, Provider().Schema, tc.Raw)
+		transport, diagnostics := newBaseTransport(d)
+		if diagnostics.HasError() {
+			t.Fatalf("%s: unexpected diagnostics: %v", tc.Name, diagnostics)
+		}
+		_, err := (&http.Client{Transport: transport}).Get(srv.URL)
+		if tc.WantErr != (err != nil) {
+			t.Errorf("%s: want error %v, got %v", tc.Name, tc.WantErr, err)
+		}
+	}
+}
+
+func TestNewBaseTransportInvalidCA(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, Provider().Schema, map[string]interface{}{
+		"ca_pem": "not a certificate",
+	})
+	if _, diagnostics := newBaseTransport(d); !diagnostics.HasError() {
+		t.Fatal("expected an error for an invalid CA bundle")
+	}
+}
+
+func TestNewProxyFunc(t *testing.T) {
+	proxy, diagnostics := newProxyFunc("http://proxy.example.com:3128", "internal.example.com,.svc")
+	if diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	cases := []struct {
+		URL  string
+		Want string
+	}{
+		{"https://metakube.syseleven.de/api/v2/projects", "http://proxy.example.com:3128"},
+		{"https://internal.example.com/api/v2/projects", ""},
+		{"https://metakube.cluster.svc/api/v2/projects", ""},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, tc.URL, nil)
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.URL, err)
+		}
+		var got string
+		if u != nil {
+			got = u.String()
+		}
+		if got != tc.Want {
+			t.Errorf("%s: want proxy %q, got %q", tc.URL, tc.Want, got)
+		}
+	}
+}
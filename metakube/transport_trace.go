@@ -0,0 +1,157 @@
+package metakube
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// bodies longer than this are truncated in the trace
+	traceMaxBody = 64 << 10
+
+	traceRedacted = "REDACTED"
+)
+
+// JSON keys are redacted if, lowercased and without '_' and '-', they end
+// with one of these suffixes. This covers tokens, passwords and cloud
+// credentials like clientSecret, secretAccessKey and applicationCredentialSecret.
+var traceRedactedKeySuffixes = []string{
+	"secret",
+	"secretaccesskey",
+	"password",
+	"token",
+	"privatekey",
+}
+
+var traceRedactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// traceTransport logs every request sent to the MetaKube API and its reply.
+// Credentials are redacted, bodies that are not JSON are omitted.
+type traceTransport struct {
+	next http.RoundTripper
+	log  *zap.Logger
+}
+
+func newTraceTransport(next http.RoundTripper, log *zap.Logger) http.RoundTripper {
+	if log == nil {
+		return next
+	}
+	return &traceTransport{next: next, log: log}
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields := []zap.Field{
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+		zap.Any("request_headers", redactHeaders(req.Header)),
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// RoundTrip must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		fields = append(fields, zap.String("request_body", traceBody(req.Header.Get("Content-Type"), body)))
+	}
+
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+	fields = append(fields, zap.Duration("latency", time.Since(start)))
+	if err != nil {
+		t.log.Debug("request failed", append(fields, zap.Error(err))...)
+		return res, err
+	}
+
+	fields = append(fields,
+		zap.Int("status", res.StatusCode),
+		zap.Any("response_headers", redactHeaders(res.Header)),
+	)
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		t.log.Debug("reading response failed", append(fields, zap.Error(err))...)
+		return res, nil
+	}
+	fields = append(fields, zap.String("response_body", traceBody(res.Header.Get("Content-Type"), body)))
+	t.log.Debug("request", fields...)
+	return res, nil
+}
+
+func redactHeaders(h http.Header) http.Header {
+	ret := h.Clone()
+	for _, name := range traceRedactedHeaders {
+		if ret.Get(name) != "" {
+			ret.Set(name, traceRedacted)
+		}
+	}
+	return ret
+}
+
+// traceBody returns the body to log. Only JSON documents are logged, after
+// redacting sensitive values.
+func traceBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	if !strings.Contains(contentType, "json") || json.Unmarshal(body, &v) != nil {
+		return fmt.Sprintf("<omitted %d bytes of %q>", len(body), contentType)
+	}
+	raw, err := json.Marshal(redactJSON(v))
+	if err != nil {
+		return fmt.Sprintf("<omitted %d bytes of %q>", len(body), contentType)
+	}
+	if len(raw) > traceMaxBody {
+		return string(raw[:traceMaxBody]) + "...<truncated>"
+	}
+	return string(raw)
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			if isSensitiveKey(k) {
+				if item != nil && item != "" {
+					vv[k] = traceRedacted
+				}
+				continue
+			}
+			vv[k] = redactJSON(item)
+		}
+	case []interface{}:
+		for i, item := range vv {
+			vv[i] = redactJSON(item)
+		}
+	}
+	return v
+}
+
+func isSensitiveKey(k string) bool {
+	k = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(k))
+	for _, s := range traceRedactedKeySuffixes {
+		if strings.HasSuffix(k, s) {
+			return true
+		}
+	}
+	return false
+}
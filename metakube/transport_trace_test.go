@@ -0,0 +1,138 @@
+package metakube
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestTraceTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(b), "s3cr3t") {
+			t.Errorf("request body sent to the API must not be redacted: %s", b)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"c1","spec":{"cloud":{"aws":{"accessKeyID":"AKIA","secretAccessKey":"aws-secret"}}}}`)
+	}))
+	defer srv.Close()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	client := &http.Client{Transport: newTraceTransport(http.DefaultTransport, zap.New(core))}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v2/projects/p1/clusters", strings.NewReader(
+		`{"name":"test","spec":{"cloud":{"openstack":{"applicationCredentialID":"id","applicationCredentialSecret":"s3cr3t"},"azure":{"client_secret":"s3cr3t"}}},"password":"s3cr3t"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer t0ken")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	if !strings.Contains(string(body), "aws-secret") {
+		t.Fatalf("response body passed on must not be redacted: %s", body)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("want 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	for _, f := range []string{"method", "url", "status", "latency", "request_body", "response_body"} {
+		if _, ok := fields[f]; !ok {
+			t.Errorf("missing field %s", f)
+		}
+	}
+	if fields["status"] != int64(http.StatusOK) {
+		t.Errorf("want status 200, got %v", fields["status"])
+	}
+	for _, f := range []string{"request_headers", "request_body", "response_body"} {
+		v := strings.ToLower(fmtField(fields[f]))
+		for _, secret := range []string{"s3cr3t", "t0ken", "aws-secret"} {
+			if strings.Contains(v, secret) {
+				t.Errorf("%s leaks %s: %s", f, secret, v)
+			}
+		}
+	}
+	if !strings.Contains(fmtField(fields["response_body"]), "AKIA") {
+		t.Errorf("non sensitive values should be kept: %v", fields["response_body"])
+	}
+}
+
+func fmtField(v interface{}) string {
+	if h, ok := v.(http.Header); ok {
+		var b strings.Builder
+		_ = h.Write(&b)
+		return b.String()
+	}
+	s, _ := v.(string)
+	return s
+}
+
+func TestTraceTransportKeepsRequest(t *testing.T) {
+	var sent string
+	next := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(r.Body)
+		sent = string(b)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})
+	req, _ := http.NewRequest(http.MethodPost, "https://metakube.example.com/api/v2/projects", strings.NewReader(`{"name":"p1"}`))
+	body, getBody := req.Body, req.GetBody
+
+	if _, err := newTraceTransport(next, zap.NewNop()).RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sent != `{"name":"p1"}` {
+		t.Fatalf("want the request body sent, got %q", sent)
+	}
+	if req.Body != body || fmt.Sprintf("%p", req.GetBody) != fmt.Sprintf("%p", getBody) {
+		t.Fatal("the caller's request was modified")
+	}
+}
+
+func TestTraceBody(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "empty",
+			contentType: "application/json",
+			want:        "",
+		},
+		{
+			name:        "redacted keys",
+			contentType: "application/json",
+			body:        `{"items":[{"token":"a","name":"b"}],"clientSecret":"c","refresh_token":"d","emptyPassword":""}`,
+			want:        `{"clientSecret":"REDACTED","emptyPassword":"","items":[{"name":"b","token":"REDACTED"}],"refresh_token":"REDACTED"}`,
+		},
+		{
+			name:        "kubeconfig",
+			contentType: "application/octet-stream",
+			body:        "apiVersion: v1\nusers:\n- user:\n    token: abc\n",
+			want:        `<omitted 45 bytes of "application/octet-stream">`,
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        `{"token":`,
+			want:        `<omitted 9 bytes of "application/json">`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := traceBody(tc.contentType, []byte(tc.body)); got != tc.want {
+				t.Fatalf("want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}